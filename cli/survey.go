@@ -7,6 +7,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/rollbar/rollbar-go"
 	"github.com/urfave/cli"
+	"github.com/warpdotdev/warp-cli-survey/history"
+	"github.com/warpdotdev/warp-cli-survey/record"
 	"github.com/warpdotdev/warp-cli-survey/store"
 	"github.com/warpdotdev/warp-cli-survey/survey"
 )
@@ -17,26 +19,36 @@ func main() {
 	var respondentID string
 	var serverRoot string
 	var historyFile string
+	var historyDir string
+	var subcommandRegistry string
 
 	rollbar.SetToken("6754ea1d67794cc8b92d2855ac3a45db")
 	rollbar.SetEnvironment("production")
 	rollbar.SetCodeVersion("0.2.1")
 	rollbar.SetServerRoot("github.com/warpdotdev/warp-cli-survey")
-	rollbar.Info("Starting new survey...")
 
 	app := &cli.App{
 		Name:  "survey",
 		Usage: "Run the Warp survey",
 		Action: func(c *cli.Context) error {
+			rollbar.Info("Starting new survey...")
 			err := rollbar.WrapAndWait(func() {
 				storage := store.NewWebStore(serverRoot)
 				emailer := store.NewEmailer(serverRoot)
 				respondentID = uuid.New().String()
-				if len(historyFile) == 0 {
-					survey.Start(storage, emailer, respondentID, nil)
-				} else {
-					survey.Start(storage, emailer, respondentID, &historyFile)
+				if len(subcommandRegistry) > 0 {
+					if err := history.LoadSubcommandRegistry(subcommandRegistry); err != nil {
+						log.Println("Unable to load subcommand registry", subcommandRegistry, err)
+					}
 				}
+				var historyFilePath, historyDirPath *string
+				if len(historyFile) > 0 {
+					historyFilePath = &historyFile
+				}
+				if len(historyDir) > 0 {
+					historyDirPath = &historyDir
+				}
+				survey.Start(storage, emailer, respondentID, historyFilePath, historyDirPath)
 			})
 			if err != nil {
 				return cli.NewExitError(err, 1)
@@ -56,6 +68,68 @@ func main() {
 				Usage:       "A history file to parse",
 				Destination: &historyFile,
 			},
+			&cli.StringFlag{
+				Name:        "historyDir",
+				Value:       "",
+				Usage:       "A directory with a history file per terminal session to parse, like ~/.zsh_sessions",
+				Destination: &historyDir,
+			},
+			&cli.StringFlag{
+				Name:        "subcommandRegistry",
+				Value:       "",
+				Usage:       "A JSON file describing the subcommands of additional tools",
+				Destination: &subcommandRegistry,
+			},
+		},
+		Commands: []cli.Command{
+			{
+				Name:  "record",
+				Usage: "Record how long your commands run and whether they fail, for the survey to share with your history",
+				Subcommands: []cli.Command{
+					{
+						Name:      "install",
+						Usage:     "Add the recording hook to your shells' startup files",
+						ArgsUsage: "[bash|zsh|fish ...]",
+						Action: func(c *cli.Context) error {
+							if err := record.Install(c.Args()); err != nil {
+								return cli.NewExitError(err, 1)
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "uninstall",
+						Usage:     "Remove the recording hook from your shells' startup files",
+						ArgsUsage: "[bash|zsh|fish ...]",
+						Action: func(c *cli.Context) error {
+							if err := record.Uninstall(c.Args()); err != nil {
+								return cli.NewExitError(err, 1)
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "status",
+						Usage: "Show where the recording hook is installed and how much has been recorded",
+						Action: func(c *cli.Context) error {
+							record.Status()
+							return nil
+						},
+					},
+					{
+						// Run by the hooks after every command line
+						Name:            "log",
+						Hidden:          true,
+						SkipFlagParsing: true,
+						Action: func(c *cli.Context) error {
+							if err := record.Log(c.Args()); err != nil {
+								return cli.NewExitError(err, 1)
+							}
+							return nil
+						},
+					},
+				},
+			},
 		},
 	}
 
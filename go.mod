@@ -13,4 +13,5 @@ require (
 	github.com/stretchr/testify v1.5.1
 	github.com/urfave/cli v1.22.4
 	google.golang.org/api v0.25.0
+	mvdan.cc/sh/v3 v3.1.2
 )
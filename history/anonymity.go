@@ -0,0 +1,296 @@
+package history
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultMinCount is how many times a command, subcommand, set of flags, wrapper or
+// environment variable must appear in a history to be kept by SuppressRare
+const DefaultMinCount = 3
+
+// Placeholders for what SuppressRare suppresses
+const (
+	RareCommand    = "<rare-command>"
+	RareSubcommand = "<rare-subcommand>"
+	RareFlags      = "<rare-flags>"
+	RareWrapper    = "<rare-wrapper>"
+	RareEnvVar     = "<rare-env-var>"
+)
+
+// Environment variables so common that setting them doesn't say anything about a
+// respondent
+var commonEnvVars = map[string]bool{
+	"PATH": true, "HOME": true, "USER": true, "SHELL": true, "TERM": true, "LANG": true, "LC_ALL": true,
+	"TZ": true, "EDITOR": true, "VISUAL": true, "PAGER": true, "DEBUG": true, "VERBOSE": true, "CI": true,
+	"ENV": true, "PORT": true, "HOST": true, "NODE_ENV": true, "NODE_OPTIONS": true, "RAILS_ENV": true,
+	"RACK_ENV": true, "FLASK_ENV": true, "FLASK_APP": true, "PYTHONPATH": true, "PYTHONUNBUFFERED": true,
+	"VIRTUAL_ENV": true, "GOOS": true, "GOARCH": true, "GOPATH": true, "GOFLAGS": true, "GO111MODULE": true,
+	"CGO_ENABLED": true, "RUST_LOG": true, "RUST_BACKTRACE": true, "CARGO_HOME": true, "CC": true,
+	"CXX": true, "CFLAGS": true, "CXXFLAGS": true, "LDFLAGS": true, "JAVA_HOME": true, "JAVA_OPTS": true,
+	"DOCKER_HOST": true, "DOCKER_BUILDKIT": true, "COMPOSE_PROJECT_NAME": true, "KUBECONFIG": true,
+	"AWS_PROFILE": true, "AWS_REGION": true, "AWS_DEFAULT_REGION": true, "GIT_PAGER": true,
+	"GIT_EDITOR": true, "LOG_LEVEL": true, "HTTP_PROXY": true, "HTTPS_PROXY": true, "NO_PROXY": true,
+	"http_proxy": true, "https_proxy": true, "no_proxy": true, "DISPLAY": true, "SUDO_USER": true,
+}
+
+// builtinSubcommandTools are the tools in the built in subcommand registry, which
+// unlike tools added with LoadSubcommandRegistry count as common
+var builtinSubcommandTools = registryTools(subcommandRegistry)
+
+// builtinSubcommandPaths are the subcommand paths in the built in registry, e.g. git
+// remote add. It's parsed again as LoadSubcommandRegistry merges into the registry.
+var builtinSubcommandPaths = registryPaths(mustParseSubcommandRegistry(defaultSubcommandRegistry))
+
+// Flags so common that using them doesn't say anything about a respondent
+var commonFlags = map[string]bool{
+	"h": true, "help": true, "V": true, "version": true, "v": true, "verbose": true, "q": true, "quiet": true,
+	"f": true, "force": true, "r": true, "R": true, "recursive": true, "a": true, "all": true, "y": true,
+	"yes": true, "n": true, "dry-run": true, "d": true, "debug": true, "o": true, "output": true, "file": true,
+	"i": true, "interactive": true, "g": true, "global": true, "D": true, "save-dev": true, "S": true,
+	"save": true, "l": true, "long": true, "la": true, "al": true, "lh": true, "lah": true, "alh": true,
+	"1": true, "p": true, "parents": true, "u": true, "user": true, "m": true, "message": true, "amend": true,
+	"no-verify": true, "rf": true, "fr": true, "it": true, "rm": true, "e": true, "env": true, "t": true,
+	"tag": true, "w": true, "watch": true, "x": true, "z": true, "c": true, "s": true, "b": true, "k": true,
+	"j": true, "jobs": true, "port": true, "host": true, "namespace": true, "project": true, "region": true,
+	"profile": true, "format": true, "json": true, "color": true, "no-cache": true, "build": true,
+	"detach": true, "prefix": true, "production": true, "frozen-lockfile": true, "release": true,
+	"hard": true, "soft": true, "oneline": true, "graph": true, "stat": true, "cached": true, "staged": true,
+	"short": true, "porcelain": true, "set-upstream": true, "prune": true, "rebase": true, "ff-only": true,
+	"no-ff": true, "squash": true, "continue": true, "abort": true, "skip": true, "exec": true, "type": true,
+	"name": true, "iname": true, "maxdepth": true, "mindepth": true, "path": true, "delete": true,
+	"print": true, "newer": true, "size": true, "mtime": true, "include": true, "exclude": true, "list": true,
+	"N": true, "A": true, "B": true, "C": true, "E": true, "F": true, "H": true, "I": true, "L": true,
+	"P": true, "T": true, "U": true, "X": true, "Z": true,
+}
+
+// SuppressRare is an optional privacy pass that generalizes commands, subcommands,
+// sets of flags, wrappers and environment variables that could identify a respondent:
+// anything used fewer than minCount times, or that isn't in our bundled lists of
+// common tools, subcommands, flags and variables. Commands are replaced with
+// RareCommand, dropping their subcommands and options, subcommand paths are cut at the
+// first rare level, which becomes RareSubcommand, a command's options are replaced
+// with RareFlags, and wrappers and variables with RareWrapper and RareEnvVar.
+// Placeholders are always kept. Returns the suppressed items with how often each was
+// used, e.g. acmectl, git acme-sync, git --acme-tenant --short, doas (wrapper) or
+// ACME_TENANT=, and adds them to SuppressedRare.
+func (h *ShellHistory) SuppressRare(minCount int) map[string]int {
+	suppressed := map[string]int{}
+
+	commandCounts := map[string]int{}
+	h.forEachCommand(func(r *RedactedCommand) {
+		commandCounts[r.Command]++
+	})
+	h.forEachCommand(func(r *RedactedCommand) {
+		if isPlaceholder(r.Command) || commandCounts[r.Command] >= minCount && isCommonCommand(r.Command) {
+			return
+		}
+		suppressed[r.Command]++
+		r.Command = RareCommand
+		r.Subcommand = ""
+		r.SubcommandPath = make([]string, 0)
+		r.Options = make([]string, 0)
+	})
+
+	// Each level of a subcommand path is counted, so git remote add is kept only if
+	// git remote is
+	pathCounts := map[string]int{}
+	h.forEachCommand(func(r *RedactedCommand) {
+		for i := range r.SubcommandPath {
+			pathCounts[subcommandKey(r.Command, r.SubcommandPath[:i+1])]++
+		}
+	})
+	h.forEachCommand(func(r *RedactedCommand) {
+		for i, subcommand := range r.SubcommandPath {
+			if isPlaceholder(subcommand) {
+				continue
+			}
+			key := subcommandKey(r.Command, r.SubcommandPath[:i+1])
+			if pathCounts[key] >= minCount && isCommonSubcommand(r.Command, r.SubcommandPath[:i+1]) {
+				continue
+			}
+			suppressed[key]++
+			r.SubcommandPath = append(r.SubcommandPath[:i:i], RareSubcommand)
+			r.Subcommand = r.SubcommandPath[0]
+			break
+		}
+	})
+
+	// Flags are judged together, as a combination of common flags can be as telling
+	// as an unusual one
+	flagSetCounts := map[string]int{}
+	h.forEachCommand(func(r *RedactedCommand) {
+		if len(r.Options) > 0 {
+			flagSetCounts[flagSetKey(r)]++
+		}
+	})
+	h.forEachCommand(func(r *RedactedCommand) {
+		if len(r.Options) == 0 || len(r.Options) == 1 && r.Options[0] == RareFlags {
+			return
+		}
+		key := flagSetKey(r)
+		if flagSetCounts[key] >= minCount && isCommonFlagSet(r.Command, r.Options) {
+			return
+		}
+		suppressed[key]++
+		r.Options = []string{RareFlags}
+	})
+
+	// Wrappers all come from our own lists, so only how often they're used counts
+	wrappers := func(r *RedactedCommand) []string { return r.Wrappers }
+	h.suppressRareNames(minCount, suppressed, wrappers, nil, RareWrapper, " (wrapper)")
+	envVars := func(r *RedactedCommand) []string { return r.EnvVars }
+	h.suppressRareNames(minCount, suppressed, envVars, commonEnvVars, RareEnvVar, "=")
+
+	if h.SuppressedRare == nil {
+		h.SuppressedRare = map[string]int{}
+	}
+	for item, count := range suppressed {
+		h.SuppressedRare[item] += count
+	}
+	return suppressed
+}
+
+// suppressRareNames replaces the names in a list on each command, like its wrappers,
+// that are used fewer than minCount times or aren't in common with placeholder, and
+// counts them in suppressed as the name followed by suffix. If common is nil every
+// name is common.
+func (h *ShellHistory) suppressRareNames(minCount int, suppressed map[string]int,
+	names func(r *RedactedCommand) []string, common map[string]bool, placeholder string, suffix string) {
+	counts := map[string]int{}
+	h.forEachCommand(func(r *RedactedCommand) {
+		for _, name := range names(r) {
+			counts[name]++
+		}
+	})
+	h.forEachCommand(func(r *RedactedCommand) {
+		list := names(r)
+		for i, name := range list {
+			if isPlaceholder(name) || counts[name] >= minCount && (common == nil || common[name]) {
+				continue
+			}
+			suppressed[name+suffix]++
+			list[i] = placeholder
+		}
+	})
+}
+
+func (h *ShellHistory) forEachCommand(f func(r *RedactedCommand)) {
+	for _, r := range h.RedactedLines {
+		if r != nil {
+			f(r)
+		}
+	}
+}
+
+func subcommandKey(command string, path []string) string {
+	return strings.Join(append([]string{command}, path...), " ")
+}
+
+// flagSetKey identifies a command's set of flags, ignoring their order and repeats
+func flagSetKey(r *RedactedCommand) string {
+	seen := map[string]bool{}
+	flags := make([]string, 0, len(r.Options))
+	for _, option := range r.Options {
+		if !seen[option] {
+			seen[option] = true
+			flags = append(flags, "--"+option)
+		}
+	}
+	sort.Strings(flags)
+	return subcommandKey(r.Command, r.SubcommandPath) + " " + strings.Join(flags, " ")
+}
+
+// isPlaceholder returns true if a command or subcommand has already been replaced by
+// a placeholder, which doesn't say anything about a respondent
+func isPlaceholder(name string) bool {
+	return strings.HasPrefix(name, "<")
+}
+
+// isCommonCommand returns true if a command is a well known one. Tools added with
+// LoadSubcommandRegistry are known, but not common.
+func isCommonCommand(command string) bool {
+	if builtinSubcommandTools[command] {
+		return true
+	}
+	if _, ok := subcommandRegistry[command]; ok {
+		return false
+	}
+	return isKnownCommand(command)
+}
+
+// isCommonSubcommand returns true if a subcommand path is in the built in registry.
+// Placeholders in the path, like UnknownSubcommand, are common.
+func isCommonSubcommand(command string, path []string) bool {
+	for _, subcommand := range path {
+		if isPlaceholder(subcommand) {
+			return true
+		}
+	}
+	return builtinSubcommandPaths[subcommandKey(command, path)]
+}
+
+// isCommonFlagSet returns true if every flag in a set is common
+func isCommonFlagSet(command string, options []string) bool {
+	for _, option := range options {
+		if !isCommonFlag(command, option) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCommonFlag returns true if a flag is common, or is a canonical flag of the command
+func isCommonFlag(command string, option string) bool {
+	if commonFlags[option] {
+		return true
+	}
+	rule, ok := flagGlueRules[command]
+	if !ok {
+		return false
+	}
+	for _, name := range rule.valueFlags {
+		if name == option {
+			return true
+		}
+	}
+	for _, name := range rule.boolFlags {
+		if name == option {
+			return true
+		}
+	}
+	for _, name := range rule.prefixFlags {
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+func registryTools(registry map[string]*SubcommandTree) map[string]bool {
+	tools := map[string]bool{}
+	for tool := range registry {
+		tools[tool] = true
+	}
+	return tools
+}
+
+// registryPaths returns every subcommand path in a registry, e.g. git remote add
+func registryPaths(registry map[string]*SubcommandTree) map[string]bool {
+	paths := map[string]bool{}
+	for tool, tree := range registry {
+		addSubcommandPaths(paths, tool, tree)
+	}
+	return paths
+}
+
+func addSubcommandPaths(paths map[string]bool, prefix string, tree *SubcommandTree) {
+	if tree == nil {
+		return
+	}
+	for name, subtree := range tree.Subcommands {
+		path := prefix + " " + name
+		paths[path] = true
+		addSubcommandPaths(paths, path, subtree)
+	}
+}
@@ -0,0 +1,94 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestSuppressRare(t *testing.T) {
+	registryFile, err := ioutil.TempFile("", "subcommands*.json")
+	assert.Nil(t, err)
+	defer os.Remove(registryFile.Name())
+	registryFile.WriteString(`{"initech": {"valueFlags": ["--env"], "leaves": ["deploy"]},
+"git": {"subcommands": {"flow": {"leaves": ["feature"]}}}}`)
+	registryFile.Close()
+	assert.Nil(t, LoadSubcommandRegistry(registryFile.Name()))
+	defer delete(subcommandRegistry, "initech")
+	defer delete(subcommandRegistry["git"].Subcommands, "flow")
+
+	historyFile, err := ioutil.TempFile("", ".bash_history")
+	assert.Nil(t, err)
+	defer os.Remove(historyFile.Name())
+	historyFile.WriteString(`git status --short
+git status --short
+git log --oneline --acme-format
+git log --acme-format --oneline
+git stash pop
+traceroute example.com
+initech deploy --env prod
+initech deploy --env staging
+git flow feature
+git flow feature
+./deploy.sh
+ls -l -a
+ls -a -l
+DEBUG=1 ls
+DEBUG=1 ls
+ACME_TENANT_ID=42 ls
+ACME_TENANT_ID=7 ls
+sudo ls
+nohup ls
+nohup ls
+`)
+	historyFile.Close()
+
+	path := historyFile.Name()
+	history := RedactHistoryFile(&path, shell.Bash)
+	assert.NotNil(t, history)
+	assert.Equal(t, 20, len(history.RedactedLines))
+
+	suppressed := history.SuppressRare(2)
+	assert.Equal(t, map[string]int{
+		// Uncommon, though used often enough
+		"git log --acme-format --oneline": 2,
+		"initech":                         2,
+		"git flow":                        2,
+		"ACME_TENANT_ID=":                 2,
+		// Common, but used too rarely
+		"git stash":      1,
+		"traceroute":     1,
+		"sudo (wrapper)": 1,
+	}, suppressed)
+	assert.Equal(t, suppressed, history.SuppressedRare)
+
+	lines := history.RedactedLines
+	assert.Equal(t, []string{"status"}, lines[0].SubcommandPath)
+	assert.Equal(t, []string{"short"}, lines[0].Options)
+	assert.Equal(t, []string{"log"}, lines[2].SubcommandPath)
+	assert.Equal(t, []string{RareFlags}, lines[2].Options)
+	assert.Equal(t, "git", lines[4].Command)
+	assert.Equal(t, RareSubcommand, lines[4].Subcommand)
+	assert.Equal(t, []string{RareSubcommand}, lines[4].SubcommandPath)
+	assert.Equal(t, RareCommand, lines[5].Command)
+	assert.Equal(t, RareCommand, lines[6].Command)
+	assert.Equal(t, []string{}, lines[6].SubcommandPath)
+	assert.Equal(t, []string{}, lines[6].Options)
+	assert.Equal(t, []string{RareSubcommand}, lines[8].SubcommandPath)
+	assert.Equal(t, relativeScriptCommand, lines[10].Command)
+	// The same flags in a different order are the same set
+	assert.Equal(t, []string{"l", "a"}, lines[11].Options)
+	assert.Equal(t, []string{"a", "l"}, lines[12].Options)
+	assert.Equal(t, []string{"DEBUG"}, lines[13].EnvVars)
+	assert.Equal(t, []string{RareEnvVar}, lines[15].EnvVars)
+	assert.Equal(t, []string{RareEnvVar}, lines[16].EnvVars)
+	assert.Equal(t, []string{RareWrapper}, lines[17].Wrappers)
+	assert.Equal(t, "ls", lines[17].Command)
+	assert.Equal(t, []string{"nohup"}, lines[18].Wrappers)
+
+	// Suppressing again has nothing left to hide
+	assert.Equal(t, map[string]int{}, history.SuppressRare(2))
+}
@@ -0,0 +1,89 @@
+package history
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// Atuin (https://atuin.sh) keeps history for every shell in a SQLite database,
+// with a history table holding a row per command. Timestamps and durations are in
+// nanoseconds, and a duration or exit code of -1 means it wasn't recorded.
+
+const atuinHistoryQuery = `SELECT command, timestamp, duration, exit, cwd
+FROM history WHERE deleted_at IS NULL ORDER BY timestamp`
+
+// Older versions of atuin don't soft-delete history and have no deleted_at column
+const atuinLegacyHistoryQuery = `SELECT command, timestamp, duration, exit, cwd
+FROM history ORDER BY timestamp`
+
+// Column indexes for atuinHistoryQuery
+const (
+	atuinCommandColumn = iota
+	atuinTimestampColumn
+	atuinDurationColumn
+	atuinExitColumn
+	atuinCwdColumn
+	atuinNumColumns
+)
+
+// GetAtuinHistoryFile returns the path of the atuin history database, if there is one
+func GetAtuinHistoryFile() (string, error) {
+	if !sqliteAvailable() {
+		return "", errors.New("sqlite3 is not installed")
+	}
+	dbPath := os.Getenv("ATUIN_DB_PATH")
+	if len(dbPath) == 0 {
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if len(dataHome) == 0 {
+			dataHome = os.ExpandEnv("$HOME/.local/share")
+		}
+		dbPath = dataHome + "/atuin/history.db"
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return "", err
+	}
+	return dbPath, nil
+}
+
+// RedactAtuinHistory redacts every command in an atuin history database, splitting
+// command lines with the rules for the given shell type.
+// Returns nil if the database can't be read.
+func RedactAtuinHistory(dbPath string, shellType shell.Type) *ShellHistory {
+	log.Println("Reading atuin history database", dbPath)
+	rows, err := querySQLite(dbPath, atuinHistoryQuery)
+	if err != nil {
+		rows, err = querySQLite(dbPath, atuinLegacyHistoryQuery)
+	}
+	if err != nil {
+		log.Println("Error reading atuin history database", err)
+		return nil
+	}
+
+	history := newShellHistory(dbPath, shellType)
+	for _, row := range rows {
+		if len(row) != atuinNumColumns {
+			history.UnattributedLines++
+			continue
+		}
+		var timestamp time.Time
+		if timestampNs, err := strconv.ParseInt(row[atuinTimestampColumn], 10, 64); err == nil {
+			timestamp = time.Unix(0, timestampNs)
+		}
+		var duration time.Duration
+		if durationNs, err := strconv.ParseInt(row[atuinDurationColumn], 10, 64); err == nil && durationNs >= 0 {
+			duration = time.Duration(durationNs)
+		}
+		var exitStatus *int
+		if status, err := strconv.Atoi(row[atuinExitColumn]); err == nil && status >= 0 {
+			exitStatus = &status
+		}
+		commands := history.redactEntry(shellType, []string{row[atuinCommandColumn]})
+		setCommandLineRun(commands, timestamp, duration, exitStatus, row[atuinCwdColumn])
+	}
+	return history
+}
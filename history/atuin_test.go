@@ -0,0 +1,47 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestRedactAtuinHistory(t *testing.T) {
+	if !sqliteAvailable() {
+		t.Skip("sqlite3 is not installed")
+	}
+	dir, err := ioutil.TempDir("", "atuin")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "history.db")
+	err = exec.Command("sqlite3", dbPath, `
+CREATE TABLE history (id TEXT PRIMARY KEY, timestamp INTEGER, duration INTEGER, exit INTEGER,
+  command TEXT, cwd TEXT, session TEXT, hostname TEXT, deleted_at INTEGER);
+INSERT INTO history VALUES ('b', 1591025340000000000, -1, -1, 'ls -la', '/tmp', 's', 'h', NULL);
+INSERT INTO history VALUES ('a', 1591025337000000000, 2500000000, 1, 'git push --force', '/opt/x', 's', 'h', NULL);
+INSERT INTO history VALUES ('c', 1591025345000000000, 1, 0, 'rm -rf secret', '/tmp', 's', 'h', 1591025346000000000);`).Run()
+	assert.Nil(t, err)
+
+	history := RedactAtuinHistory(dbPath, shell.Zsh)
+	assert.NotNil(t, history)
+	assert.Equal(t, 2, len(history.RedactedLines))
+
+	r := history.RedactedLines[0]
+	assert.Equal(t, "git", r.Command)
+	assert.Equal(t, time.Unix(1591025337, 0), r.Timestamp)
+	assert.Equal(t, 2500*time.Millisecond, r.Duration)
+	assert.Equal(t, 1, *r.ExitStatus)
+	assert.Equal(t, "/*/*", r.Directory)
+
+	r = history.RedactedLines[1]
+	assert.Equal(t, "ls", r.Command)
+	assert.Equal(t, time.Duration(0), r.Duration)
+	assert.Nil(t, r.ExitStatus)
+}
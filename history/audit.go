@@ -0,0 +1,203 @@
+package history
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// AuditSpan is a piece of the original text of a command, and whether it is uploaded
+// in some form or removed by redaction
+type AuditSpan struct {
+	Text    string
+	Removed bool
+}
+
+// auditWord is the position of a word in the original text of a command
+type auditWord struct {
+	start int
+	end   int
+}
+
+// Audit splits the original text of the command into spans that are kept and spans
+// that are removed, so the user can check the redaction against what they typed.
+// Subcommands are only kept if withSubcommands is set, and environment variables,
+// wrappers and options only if withDetails is. The original text is only ever
+// available through this method, for showing to the user locally.
+func (r *RedactedCommand) Audit(withSubcommands bool, withDetails bool) []AuditSpan {
+	spans := make([]AuditSpan, 0)
+	add := func(text string, removed bool) {
+		if len(text) == 0 {
+			return
+		}
+		if len(spans) > 0 && spans[len(spans)-1].Removed == removed {
+			spans[len(spans)-1].Text += text
+			return
+		}
+		spans = append(spans, AuditSpan{Text: text, Removed: removed})
+	}
+
+	envVars := stringSet(r.EnvVars)
+	options := stringSet(r.Options)
+	wrappers := r.Wrappers
+	commandFound := false
+	pathIndex := 0
+	last := 0
+	for _, word := range splitAuditWords(r.source) {
+		add(r.source[last:word.start], false)
+		last = word.end
+		text := r.source[word.start:word.end]
+		unquoted := unquoteWord(text)
+		kept := 0
+
+		switch {
+		case commandFound && withSubcommands && pathIndex < len(r.SubcommandPath) &&
+			unquoted == r.SubcommandPath[pathIndex]:
+			kept = len(text)
+			pathIndex++
+		case commandFound && withSubcommands && pathIndex < len(r.SubcommandPath) &&
+			r.SubcommandPath[pathIndex] == UnknownSubcommand && !strings.HasPrefix(unquoted, "-"):
+			pathIndex++
+		case commandFound && withDetails && strings.HasPrefix(text, "-"):
+			kept = r.auditOption(text, options)
+		case commandFound:
+		case assignmentRegEx.MatchString(text):
+			if match := assignmentRegEx.FindStringSubmatch(text); withDetails && envVars[match[1]] {
+				kept = len(match[0])
+			}
+		case len(wrappers) > 0 && filepath.Base(unquoted) == wrappers[0]:
+			if withDetails {
+				kept = len(text)
+			}
+			wrappers = wrappers[1:]
+		case len(wrappers) == 0 && isQuotedCommand(text, unquoted):
+			// watch and friends are often passed the whole command as one argument
+			inner := *r
+			inner.source = unquoted
+			inner.Wrappers = nil
+			add(text[:1], false)
+			for _, span := range inner.Audit(withSubcommands, withDetails) {
+				add(span.Text, span.Removed)
+			}
+			add(text[len(text)-1:], false)
+			commandFound = true
+			continue
+		case len(wrappers) == 0 && normalizeCommand(unquoted) == r.Command:
+			commandFound = true
+			if !strings.HasPrefix(r.Command, "<") {
+				// Only the name of the command is kept, not its directory
+				kept = len(text)
+				if i := strings.LastIndex(text, "/"); i >= 0 {
+					add(text[:i+1], true)
+					text = text[i+1:]
+					kept = len(text)
+				}
+			}
+		}
+		add(text[:kept], false)
+		add(text[kept:], true)
+	}
+	add(r.source[last:], false)
+	return spans
+}
+
+// auditOption returns how much of an option's original text is kept, e.g. --file
+// of --file=x, or -xz of -xzfbackup.tgz
+func (r *RedactedCommand) auditOption(text string, options map[string]bool) int {
+	dashes := len(text) - len(strings.TrimLeft(text, "-"))
+	name := text[dashes:]
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	if options[name] {
+		return dashes + len(name)
+	}
+	if dashes != 1 {
+		return 0
+	}
+
+	rule := flagGlueRules[r.Command]
+	if prefix, ok := rule.matchPrefix(name); ok && options[prefix] {
+		for flag, canonical := range rule.prefixFlags {
+			if canonical == prefix && strings.HasPrefix(name, flag) {
+				return dashes + len(flag)
+			}
+		}
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if options[string(c)] || options[rule.boolFlags[c]] {
+			continue
+		}
+		if canonical, ok := rule.valueFlags[c]; ok && options[canonical] {
+			return dashes + i + 1
+		}
+		return dashes + i
+	}
+	return len(text)
+}
+
+// splitAuditWords returns the positions of the words in a command's text, splitting
+// on whitespace outside of quotes
+func splitAuditWords(text string) []auditWord {
+	words := make([]auditWord, 0)
+	start := -1
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+			continue
+		case c == '\\':
+			if start < 0 {
+				start = i
+			}
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\n':
+			if start >= 0 {
+				words = append(words, auditWord{start: start, end: i})
+				start = -1
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, auditWord{start: start, end: len(text)})
+	}
+	return words
+}
+
+// isQuotedCommand returns true if a word is a whole command in a single pair of quotes
+func isQuotedCommand(text string, unquoted string) bool {
+	return len(text) > 2 && (text[0] == '\'' || text[0] == '"') && text[len(text)-1] == text[0] &&
+		text[1:len(text)-1] == unquoted && strings.ContainsAny(unquoted, " \t")
+}
+
+// unquoteWord removes the quoting from a single word, if it can be parsed
+func unquoteWord(word string) string {
+	split, err := shellquote.Split(word)
+	if err != nil || len(split) != 1 {
+		return word
+	}
+	return split[0]
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,57 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestAudit(t *testing.T) {
+	r := RedactCommand(shell.Bash, []string{"FOO=bar sudo -u root /usr/bin/git commit -m 'fix the thing' --amend"})
+	assert.Equal(t, []AuditSpan{
+		{Text: "FOO=", Removed: false},
+		{Text: "bar", Removed: true},
+		{Text: " sudo ", Removed: false},
+		{Text: "-u", Removed: true},
+		{Text: " ", Removed: false},
+		{Text: "root", Removed: true},
+		{Text: " ", Removed: false},
+		{Text: "/usr/bin/", Removed: true},
+		{Text: "git commit -m ", Removed: false},
+		{Text: "'fix the thing'", Removed: true},
+		{Text: " --amend", Removed: false},
+	}, r.Audit(true, true))
+
+	assert.Equal(t, "[FOO=bar] [sudo] [-u] [root] [/usr/bin/]git [commit] [-m] ['fix the thing'] [--amend]",
+		auditString(r, false, false))
+	assert.Equal(t, "[FOO=bar] [sudo] [-u] [root] [/usr/bin/]git commit [-m] ['fix the thing'] [--amend]",
+		auditString(r, true, false))
+}
+
+func TestAuditGluedFlags(t *testing.T) {
+	assert.Equal(t, "tar -xzf[backup.tgz] -C [/tmp]", auditString(RedactCommand(shell.Bash, []string{"tar -xzfbackup.tgz -C /tmp"}), true, true))
+	assert.Equal(t, "mysql -u[root] -p[Hunter2] [mydb]", auditString(RedactCommand(shell.Bash, []string{"mysql -uroot -pHunter2 mydb"}), true, true))
+	assert.Equal(t, "java -Xmx[512m] -jar [app.jar]", auditString(RedactCommand(shell.Bash, []string{"java -Xmx512m -jar app.jar"}), true, true))
+	assert.Equal(t, "gcloud compute instances list --project[=acme-prod]",
+		auditString(RedactCommand(shell.Bash, []string{"gcloud compute instances list --project=acme-prod"}), true, true))
+}
+
+func TestAuditPlaceholders(t *testing.T) {
+	commands := RedactCommands(shell.Bash, []string{"./deploy.sh --env prod && watch 'kubectl get pods -n prod'"})
+	assert.Equal(t, "[./deploy.sh] --env [prod]", auditString(commands[0], true, true))
+	assert.Equal(t, "[watch] 'kubectl get pods [-n] [prod]'", auditString(commands[1], true, false))
+}
+
+// auditString renders an audit with the removed spans in brackets
+func auditString(r *RedactedCommand, withSubcommands bool, withDetails bool) string {
+	audit := ""
+	for _, span := range r.Audit(withSubcommands, withDetails) {
+		if span.Removed {
+			audit += "[" + span.Text + "]"
+		} else {
+			audit += span.Text
+		}
+	}
+	return audit
+}
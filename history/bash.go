@@ -0,0 +1,185 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Bash format if HISTTIMEFORMAT not set
+// <command>
+//
+// Bash format if HISTTIMEFORMAT set
+// #<timestamp>
+// <command>
+//
+// With the lithist and cmdhist options set a multi-line command is written
+// across several lines, and a file can mix timestamped and untimestamped
+// sections if HISTTIMEFORMAT was only set some of the time.
+
+var bashTimestampRegEx = regexp.MustCompile(`^#\d+$`)
+
+type bashReaderState int
+
+const (
+	// bashUntimestamped means the next line starts a command with no timestamp
+	bashUntimestamped bashReaderState = iota
+	// bashAfterTimestamp means we read a timestamp and are waiting for its command
+	bashAfterTimestamp
+	// bashInCommand means we are collecting the lines of a command
+	bashInCommand
+)
+
+// maxBashCommandLines is the most lines joined into a single multi-line command. A
+// command still incomplete by then, like echo don't with its unclosed quote, never
+// continued, and the lines after it are read as commands of their own.
+const maxBashCommandLines = 100
+
+// readBashHistory splits a bash history file into entries of either one line
+// (the command) or two (the timestamp comment and the command). Each timestamp is
+// attached to the command lines that follow it, and the lines of a multi-line
+// command are joined back together. A timestamp always starts a new entry.
+//
+// Returns the number of lines that couldn't be attributed to a timestamped command,
+// either timestamps without a command or extra commands after a timestamped one.
+func readBashHistory(reader *bufio.Reader, emit func(entry []string)) (int, error) {
+	unattributed := 0
+
+	state := bashUntimestamped
+	timestamp := ""
+	var command []string
+
+	flush := func() {
+		if len(command) > 0 {
+			text := strings.TrimSpace(strings.Join(command, "\n"))
+			if len(timestamp) > 0 {
+				emit([]string{timestamp, text})
+			} else {
+				emit([]string{text})
+			}
+		}
+		command = nil
+		timestamp = ""
+	}
+
+	// Lines to read again, after giving up on a command that never completed
+	pending := make([]string, 0)
+	atEOF := false
+	nextLine := func() (string, bool, error) {
+		if len(pending) > 0 {
+			line := pending[0]
+			pending = pending[1:]
+			return line, true, nil
+		}
+		if atEOF {
+			return "", false, nil
+		}
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			atEOF = true
+		} else if err != nil {
+			return "", false, err
+		}
+		return strings.TrimRight(line, "\r\n"), true, nil
+	}
+
+	// giveUp keeps just the first line of a command that never completed, and reads
+	// the lines after it, and the line that ended it, again
+	giveUp := func(next ...string) {
+		rest := append(append([]string{}, command[1:]...), next...)
+		command = command[:1]
+		flush()
+		pending = append(rest, pending...)
+		state = bashUntimestamped
+	}
+	incomplete := func() bool {
+		return state == bashInCommand && len(command) > 1 && bashCommandIncomplete(command)
+	}
+
+	for {
+		line, ok, err := nextLine()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			if incomplete() {
+				giveUp()
+				continue
+			}
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case bashTimestampRegEx.MatchString(trimmed):
+			if incomplete() {
+				giveUp(line)
+				continue
+			}
+			if state == bashAfterTimestamp {
+				// Two timestamps in a row, the first has no command
+				unattributed++
+			}
+			flush()
+			timestamp = trimmed
+			state = bashAfterTimestamp
+
+		case len(trimmed) == 0:
+			// Blank lines only matter inside a multi-line command
+			if state == bashInCommand {
+				command = append(command, line)
+			}
+
+		case state == bashAfterTimestamp:
+			command = []string{line}
+			state = bashInCommand
+
+		case state == bashInCommand && bashCommandIncomplete(command):
+			if len(command) >= maxBashCommandLines {
+				giveUp(line)
+				continue
+			}
+			command = append(command, line)
+
+		default:
+			if state == bashInCommand && len(timestamp) > 0 {
+				// A second command after a single timestamp: keep it, but we don't
+				// know when it ran.
+				unattributed++
+			}
+			flush()
+			command = []string{line}
+			state = bashInCommand
+		}
+	}
+	if state == bashAfterTimestamp {
+		unattributed++
+	}
+	flush()
+	return unattributed, nil
+}
+
+// bashCommandIncomplete returns true if the given lines don't yet form a complete
+// bash command, meaning the next line of the history file is a continuation of it:
+// a trailing backslash, or anything bash's parser would need more input for, like an
+// open quote, a trailing operator, an unterminated here-document or an unclosed
+// compound command.
+func bashCommandIncomplete(lines []string) bool {
+	if len(lines) == 0 {
+		return false
+	}
+	lastLine := lines[len(lines)-1]
+	if trailing := len(lastLine) - len(strings.TrimRight(lastLine, "\\")); trailing%2 == 1 {
+		return true
+	}
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	_, err := parser.Parse(strings.NewReader(strings.Join(lines, "\n")+"\n"), "")
+	if err == nil {
+		return false
+	}
+	// The parser doesn't count a here-document without its delimiter as incomplete
+	return syntax.IsIncomplete(err) || strings.Contains(err.Error(), "unclosed here-document")
+}
@@ -0,0 +1,128 @@
+package history
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func readBash(t *testing.T, file string) ([][]string, int) {
+	entries, unattributed, err := readAllEntries(readBashHistory, bufio.NewReader(strings.NewReader(file)))
+	assert.Nil(t, err)
+	return entries, unattributed
+}
+
+func TestReadBashHistoryUntimestamped(t *testing.T) {
+	entries, unattributed := readBash(t, "ls\n\ngit status\n")
+	assert.Equal(t, [][]string{{"ls"}, {"git status"}}, entries)
+	assert.Equal(t, 0, unattributed)
+}
+
+func TestReadBashHistoryMixedSections(t *testing.T) {
+	file := "ls\n" +
+		"#1591025337\n" +
+		"whois nterm.com\n" +
+		"#1591025340\n" +
+		"\n" +
+		"#1591025345\n" +
+		"git push\n" +
+		"make\n"
+	entries, unattributed := readBash(t, file)
+	assert.Equal(t, [][]string{
+		{"ls"},
+		{"#1591025337", "whois nterm.com"},
+		{"#1591025345", "git push"},
+		{"make"}}, entries)
+	assert.Equal(t, 2, unattributed)
+
+	r := RedactCommand(shell.Bash, entries[2])
+	assert.Equal(t, "git", r.Command)
+	assert.Equal(t, time.Unix(1591025345, 0), r.Timestamp)
+}
+
+func TestReadBashHistoryMultiLine(t *testing.T) {
+	file := "#1591025337\n" +
+		"for f in *; do\n" +
+		"  echo \"$f\"\n" +
+		"done\n" +
+		"#1591025340\n" +
+		"cat <<EOF\n" +
+		"# not a timestamp\n" +
+		"EOF\n" +
+		"#1591025345\n" +
+		"echo 'a\n" +
+		"b'\n"
+	entries, unattributed := readBash(t, file)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "for f in *; do\n  echo \"$f\"\ndone", entries[0][1])
+	assert.Equal(t, "cat <<EOF\n# not a timestamp\nEOF", entries[1][1])
+	assert.Equal(t, "echo 'a\nb'", entries[2][1])
+	assert.Equal(t, 0, unattributed)
+}
+
+func TestBashCommandIncomplete(t *testing.T) {
+	assert.False(t, bashCommandIncomplete([]string{"ls -la"}))
+	assert.False(t, bashCommandIncomplete([]string{"echo 'it''s' # if {"}))
+	assert.True(t, bashCommandIncomplete([]string{"make \\"}))
+	assert.True(t, bashCommandIncomplete([]string{"cat x |"}))
+	assert.True(t, bashCommandIncomplete([]string{"f() {", "  echo hi"}))
+	assert.False(t, bashCommandIncomplete([]string{"f() {", "  echo hi", "}"}))
+	assert.True(t, bashCommandIncomplete([]string{"cat <<-'END'", "\tbody"}))
+	assert.True(t, bashCommandIncomplete([]string{"for f in *; do"}))
+	assert.False(t, bashCommandIncomplete([]string{"make \\\\"}))
+	assert.False(t, bashCommandIncomplete([]string{"cat <<-'END'", "\tbody", "\tEND"}))
+}
+
+func TestBashCommandIncompleteKeywordsAsArguments(t *testing.T) {
+	assert.False(t, bashCommandIncomplete([]string{"grep if foo"}))
+	assert.False(t, bashCommandIncomplete([]string{"grep -w do x"}))
+	assert.False(t, bashCommandIncomplete([]string{"echo { case"}))
+	assert.True(t, bashCommandIncomplete([]string{"echo don't"}))
+}
+
+func TestReadBashHistoryTimestampStartsEntry(t *testing.T) {
+	entries, unattributed := readBash(t, "#1\ngrep -w do x\n#2\nls\n#3\nmake\n")
+	assert.Equal(t, [][]string{{"#1", "grep -w do x"}, {"#2", "ls"}, {"#3", "make"}}, entries)
+	assert.Equal(t, 0, unattributed)
+
+	// An unclosed quote can't swallow the commands after it
+	entries, _ = readBash(t, "#1\necho don't\n#2\nls\n#3\nmake\n")
+	assert.Equal(t, [][]string{{"#1", "echo don't"}, {"#2", "ls"}, {"#3", "make"}}, entries)
+}
+
+func TestReadBashHistoryGivesUpOnIncompleteCommands(t *testing.T) {
+	entries, _ := readBash(t, "echo don't\nls\ngit status\n")
+	assert.Equal(t, [][]string{{"echo don't"}, {"ls"}, {"git status"}}, entries)
+
+	lines := []string{"echo 'never closed"}
+	for i := 0; i < maxBashCommandLines+10; i++ {
+		lines = append(lines, "ls")
+	}
+	entries, _ = readBash(t, strings.Join(lines, "\n")+"\n")
+	assert.Equal(t, len(lines), len(entries))
+	assert.Equal(t, []string{"ls"}, entries[1])
+}
+
+func TestRedactBashHistoryAfterUnclosedQuote(t *testing.T) {
+	file, err := ioutil.TempFile("", ".bash_history")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("#1591025337\ngrep -w do x\n#1591025340\nls\n#1591025345\necho don't\n#1591025350\nmake\n")
+	file.Close()
+
+	path := file.Name()
+	history := RedactHistoryFile(&path, shell.Bash)
+	assert.NotNil(t, history)
+	assert.Equal(t, []string{"grep", "ls", "make"}, commandNames(history))
+	assert.Equal(t, time.Unix(1591025340, 0), history.RedactedLines[1].Timestamp)
+	assert.Equal(t, time.Unix(1591025350, 0), history.RedactedLines[2].Timestamp)
+	for _, r := range history.RedactedLines {
+		assert.Equal(t, "", r.Operator)
+	}
+}
@@ -0,0 +1,179 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// builtinHistoryTimeout is how long a shell's history builtin is given to print the
+// history before it is killed, in case a startup file waits for input
+const builtinHistoryTimeout = 15 * time.Second
+
+// builtinEntry is a single command printed by a shell's history builtin
+type builtinEntry struct {
+	commandTime time.Time
+	command     string
+}
+
+// builtinHistory is how to print a shell's history with its own history builtin, for
+// when there's no history file we can read. The shell is started interactively so it
+// loads its history the way it would in a terminal.
+type builtinHistory struct {
+	args []string
+
+	// parse splits the output into entries, oldest first, and counts the lines
+	// that don't belong to any. ranAt is when the shell was started.
+	parse func(output string, ranAt time.Time) ([]builtinEntry, int)
+}
+
+var builtinHistories = map[shell.Type]builtinHistory{
+	shell.Bash: {args: []string{"bash", "-ic", "HISTTIMEFORMAT='%s '; history -r; history"}, parse: parseBashBuiltinHistory},
+	shell.Zsh:  {args: []string{"zsh", "-ic", "fc -l -t %s 1"}, parse: parseZshBuiltinHistory},
+	shell.Fish: {args: []string{"fish", "-c", "history --null --show-time='%s '"}, parse: parseFishBuiltinHistory},
+}
+
+// Matches a line of bash's history builtin with HISTTIMEFORMAT='%s ': the event number,
+// a * if the entry was modified, the timestamp and the command
+var bashBuiltinRegEx = regexp.MustCompile(`^\s*\d+[* ] (\d+) (.*)$`)
+
+// Matches a line of zsh's fc -l -t %s: the event number, the timestamp and the command
+var zshBuiltinRegEx = regexp.MustCompile(`^\s*\d+\*?\s+(\d+)\s\s(.*)$`)
+
+// Matches an entry of fish's history --show-time='%s ': the timestamp and the command
+var fishBuiltinRegEx = regexp.MustCompile(`(?s)^(\d+) (.*)$`)
+
+// BuiltinHistoryCommand returns the command we would run to print the shell's history
+// with its history builtin, or false if we don't know how or the shell isn't installed
+func BuiltinHistoryCommand(shellType shell.Type) (string, bool) {
+	builtin, ok := builtinHistories[shellType]
+	if !ok {
+		return "", false
+	}
+	if _, err := exec.LookPath(builtin.args[0]); err != nil {
+		return "", false
+	}
+	return shellquote.Join(builtin.args...), true
+}
+
+// RedactBuiltinHistory starts the shell, prints its history with its history builtin,
+// and redacts the output. Only run this with the user's consent, as the shell runs
+// their startup files. Returns nil if the shell isn't supported or fails.
+func RedactBuiltinHistory(shellType shell.Type) *ShellHistory {
+	builtin, ok := builtinHistories[shellType]
+	if !ok {
+		return nil
+	}
+	commandLine, _ := BuiltinHistoryCommand(shellType)
+	log.Println("Reading history with", commandLine)
+
+	ranAt := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), builtinHistoryTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, builtin.args[0], builtin.args[1:]...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	// Startup files and shells without a terminal print warnings we don't need
+	cmd.Stderr = nil
+	if err := cmd.Run(); err != nil && out.Len() == 0 {
+		log.Println("Error reading history with", commandLine, err)
+		return nil
+	}
+
+	entries, unattributed := builtin.parse(out.String(), ranAt)
+	history := newShellHistory(commandLine, shellType)
+	history.UnattributedLines = unattributed
+	for _, entry := range entries {
+		history.Add(redactParsedEntry(shellType, entry.commandTime, 0, entry.command))
+	}
+	return history
+}
+
+// parseBashBuiltinHistory parses the output of bash's history builtin with
+// HISTTIMEFORMAT='%s '. bash reads each line of a multi-line command in a history file
+// as its own event, and gives the events it has no time for the time it read them,
+// so the output is written back out as a history file, with the timestamps from
+// before ranAt, and read like one. Lines without an event number continue the command
+// before them.
+func parseBashBuiltinHistory(output string, ranAt time.Time) ([]builtinEntry, int) {
+	unattributed := 0
+	readAt := ranAt.Truncate(time.Second)
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if match := bashBuiltinRegEx.FindStringSubmatch(line); match != nil {
+			if commandTime := unixTime(match[1]); !commandTime.IsZero() && commandTime.Before(readAt) {
+				lines = append(lines, "#"+match[1])
+			}
+			lines = append(lines, match[2])
+		} else if len(lines) > 0 {
+			lines = append(lines, line)
+		} else if len(strings.TrimSpace(line)) > 0 {
+			unattributed++
+		}
+	}
+
+	entries := make([]builtinEntry, 0)
+	historyFile := bufio.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	fileUnattributed, _ := readBashHistory(historyFile, func(entry []string) {
+		commandTime, _, command := ParseLines(shell.Bash, entry)
+		entries = append(entries, builtinEntry{commandTime: commandTime, command: command})
+	})
+	return entries, unattributed + fileUnattributed
+}
+
+// parseZshBuiltinHistory parses the output of zsh's fc -l -t %s. zsh prints newlines
+// in commands as \n, and a zero timestamp for commands it has no time for.
+func parseZshBuiltinHistory(output string, _ time.Time) ([]builtinEntry, int) {
+	entries := make([]builtinEntry, 0)
+	unattributed := 0
+	for _, line := range strings.Split(output, "\n") {
+		match := zshBuiltinRegEx.FindStringSubmatch(line)
+		if match == nil {
+			if len(strings.TrimSpace(line)) > 0 {
+				unattributed++
+			}
+			continue
+		}
+		entries = append(entries, builtinEntry{commandTime: unixTime(match[1]), command: match[2]})
+	}
+	return entries, unattributed
+}
+
+// parseFishBuiltinHistory parses the output of fish's history --null --show-time='%s ',
+// which separates entries with NUL and lists the newest first
+func parseFishBuiltinHistory(output string, _ time.Time) ([]builtinEntry, int) {
+	entries := make([]builtinEntry, 0)
+	unattributed := 0
+	records := strings.Split(output, "\x00")
+	for i := len(records) - 1; i >= 0; i-- {
+		record := strings.TrimLeft(records[i], "\n")
+		if len(strings.TrimSpace(record)) == 0 {
+			continue
+		}
+		match := fishBuiltinRegEx.FindStringSubmatch(record)
+		if match == nil {
+			unattributed++
+			continue
+		}
+		entries = append(entries, builtinEntry{commandTime: unixTime(match[1]), command: match[2]})
+	}
+	return entries, unattributed
+}
+
+// unixTime parses a timestamp in seconds, returning a zero time if it isn't one
+func unixTime(seconds string) time.Time {
+	timestampSecs, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || timestampSecs <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestampSecs, 0)
+}
@@ -0,0 +1,91 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestParseBashBuiltinHistory(t *testing.T) {
+	// Events bash has no time for are given the time it read them, after ranAt
+	ranAt := time.Unix(1584112400, 0)
+	entries, unattributed := parseBashBuiltinHistory("Welcome!\n    1  1584112360 ls -la\n"+
+		"    2* 1584112370 git status\n    3  1584112380 for i in 1 2; do\n    4  1584112401 echo $i\n"+
+		"    5  1584112401 done\n    6  1584112390 echo 'a\nb'\n    7  1584112401 pwd\n", ranAt)
+	// Welcome! and pwd, which we don't know the time of
+	assert.Equal(t, 2, unattributed)
+	assert.Equal(t, []builtinEntry{
+		{commandTime: time.Unix(1584112360, 0), command: "ls -la"},
+		{commandTime: time.Unix(1584112370, 0), command: "git status"},
+		{commandTime: time.Unix(1584112380, 0), command: "for i in 1 2; do\necho $i\ndone"},
+		{commandTime: time.Unix(1584112390, 0), command: "echo 'a\nb'"},
+		{command: "pwd"},
+	}, entries)
+}
+
+func TestParseBashBuiltinHistoryIncompleteCommands(t *testing.T) {
+	ranAt := time.Unix(1584112400, 0)
+	// A timestamp starts a new command, and a quote that's never closed doesn't swallow
+	// the commands after it
+	entries, _ := parseBashBuiltinHistory("    1  1584112360 echo don't\n    2  1584112370 ls\n"+
+		"    3  1584112401 echo isn't\n    4  1584112401 pwd\n    5  1584112401 if true; then\n"+
+		"    6  1584112401 git status\n", ranAt)
+	assert.Equal(t, []builtinEntry{
+		{commandTime: time.Unix(1584112360, 0), command: "echo don't"},
+		{commandTime: time.Unix(1584112370, 0), command: "ls"},
+		{command: "echo isn't"},
+		{command: "pwd"},
+		{command: "if true; then"},
+		{command: "git status"},
+	}, entries)
+}
+
+func TestParseZshBuiltinHistory(t *testing.T) {
+	entries, unattributed := parseZshBuiltinHistory("    1  1584112360  ls -la\n    2  0  git status\nzsh: warning\n", time.Now())
+	assert.Equal(t, 1, unattributed)
+	assert.Equal(t, []builtinEntry{
+		{commandTime: time.Unix(1584112360, 0), command: "ls -la"},
+		{command: "git status"},
+	}, entries)
+}
+
+func TestParseFishBuiltinHistory(t *testing.T) {
+	entries, unattributed := parseFishBuiltinHistory("1584112370 git status\x001584112360 for i in 1 2\n  echo $i\nend\x00", time.Now())
+	assert.Equal(t, 0, unattributed)
+	assert.Equal(t, []builtinEntry{
+		{commandTime: time.Unix(1584112360, 0), command: "for i in 1 2\n  echo $i\nend"},
+		{commandTime: time.Unix(1584112370, 0), command: "git status"},
+	}, entries)
+}
+
+func TestRedactBuiltinHistory(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+	// The user's HISTTIMEFORMAT is replaced, so dates aren't read as commands
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(home, ".bashrc"),
+		[]byte("HISTFILE=~/.history/bash\nHISTTIMEFORMAT='%F %T '\n"), 0644))
+	assert.Nil(t, os.Mkdir(filepath.Join(home, ".history"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(home, ".history", "bash"),
+		[]byte("#1584112360\nls -la\nexport GITHUB_TOKEN="+fakeGitHubToken+"\ngit status\n"), 0644))
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	h := RedactBuiltinHistory(shell.Bash)
+	assert.NotNil(t, h)
+	assert.Equal(t, []string{"ls", "git"}, commandNames(h))
+	assert.Equal(t, []string{"la"}, h.RedactedLines[0].Options)
+	assert.Equal(t, time.Unix(1584112360, 0), h.RedactedLines[0].Timestamp)
+	assert.True(t, h.RedactedLines[1].Timestamp.IsZero())
+	assert.Equal(t, map[string]int{secretGitHubToken: 1}, h.SuppressedLines)
+	assert.Nil(t, RedactBuiltinHistory(shell.PowerShell))
+}
@@ -0,0 +1,233 @@
+package history
+
+import (
+	"path"
+	"strings"
+)
+
+// Placeholders for command names we don't upload
+const (
+	relativeScriptCommand = "<relative-script>"
+	absolutePathCommand   = "<absolute-path>"
+	customCommand         = "<custom>"
+)
+
+// Well known commands whose names are safe to upload. Anything else, like a script
+// or a personal alias, is replaced with a placeholder by normalizeCommand. Commands
+// in the subcommand registry, wrappers and commands with flag glue rules are known too.
+var knownCommands = map[string]bool{
+	// Shell builtins and keywords
+	"alias": true, "bg": true, "bind": true, "break": true, "builtin": true, "cd": true, "complete": true,
+	"continue": true, "declare": true, "dirs": true, "disown": true, "echo": true, "eval": true, "exit": true,
+	"export": true, "false": true, "fc": true, "fg": true, "functions": true, "hash": true, "help": true,
+	"history": true, "jobs": true, "kill": true, "let": true, "local": true, "logout": true, "popd": true,
+	"printf": true, "pushd": true, "pwd": true, "read": true, "readonly": true, "return": true, "set": true,
+	"setopt": true, "shift": true, "source": true, ".": true, "test": true, "[": true, "[[": true, "trap": true,
+	"true": true, "type": true, "typeset": true, "ulimit": true, "umask": true, "unalias": true, "unset": true,
+	"unsetopt": true, "wait": true, "which": true, "whence": true, "where": true, "rehash": true, "compinit": true,
+	"autoload": true, "zle": true, "bindkey": true, "abbr": true, "funced": true, "funcsave": true, "z": true,
+
+	// Shells
+	"sh": true, "bash": true, "zsh": true, "fish": true, "nu": true, "pwsh": true, "powershell": true,
+	"dash": true, "ksh": true, "tcsh": true, "csh": true, "elvish": true, "xonsh": true,
+
+	// Files and text
+	"ls": true, "ll": true, "la": true, "l": true, "cat": true, "less": true, "more": true, "cp": true, "mv": true,
+	"rm": true, "rmdir": true, "mkdir": true, "touch": true, "ln": true, "chmod": true, "chown": true,
+	"chgrp": true, "find": true, "locate": true, "file": true, "stat": true, "du": true, "df": true,
+	"tree": true, "wc": true, "uniq": true, "tr": true, "tee": true, "diff": true, "patch": true, "cmp": true,
+	"comm": true, "paste": true, "join": true, "split": true, "column": true, "nl": true, "fold": true,
+	"fmt": true, "rev": true, "tac": true, "strings": true, "od": true, "hexdump": true, "xxd": true,
+	"base64": true, "md5sum": true, "md5": true, "sha1sum": true, "sha256sum": true, "shasum": true,
+	"realpath": true, "readlink": true, "basename": true, "dirname": true, "mktemp": true, "install": true,
+	"dd": true, "gzip": true, "gunzip": true, "zcat": true, "bzip2": true, "xz": true, "unxz": true,
+	"zstd": true, "jq": true, "yq": true, "xmllint": true, "bat": true, "exa": true, "eza": true, "fd": true,
+	"rg": true, "ag": true, "ack": true, "fzf": true, "lsd": true, "delta": true, "open": true, "xdg-open": true,
+	"pbcopy": true, "pbpaste": true, "xclip": true, "xsel": true, "clear": true, "reset": true, "tput": true,
+	"date": true, "cal": true, "sleep": true, "seq": true, "yes": true, "expr": true, "bc": true,
+	"envsubst": true, "printenv": true, "man": true, "info": true, "tldr": true, "apropos": true,
+
+	// Editors
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "code": true, "subl": true,
+	"atom": true, "mate": true, "hx": true, "micro": true, "kak": true, "idea": true, "cursor": true,
+
+	// Processes and system
+	"ps": true, "top": true, "htop": true, "btop": true, "pkill": true, "pgrep": true, "killall": true,
+	"lsof": true, "free": true, "uptime": true, "uname": true, "whoami": true, "id": true, "who": true,
+	"w": true, "hostname": true, "env": true, "su": true, "passwd": true, "shutdown": true, "reboot": true,
+	"mount": true, "umount": true, "lsblk": true, "fdisk": true, "journalctl": true, "service": true,
+	"launchctl": true, "dmesg": true, "crontab": true, "at": true, "screen": true, "tmux": true,
+	"zellij": true, "strace": true, "dtrace": true, "ltrace": true, "gdb": true, "lldb": true,
+	"valgrind": true, "perf": true, "defaults": true, "diskutil": true, "softwareupdate": true,
+	"xcode-select": true, "xcrun": true, "sysctl": true, "lscpu": true, "vmstat": true, "iostat": true,
+	"neofetch": true, "fastfetch": true, "mas": true, "port": true, "dnf": true, "yum": true, "pacman": true,
+	"yay": true, "zypper": true, "apk": true, "snap": true, "flatpak": true, "dpkg": true, "rpm": true,
+	"nix": true, "nix-env": true, "nix-shell": true, "choco": true, "scoop": true, "winget": true,
+
+	// Network
+	"ping": true, "traceroute": true, "dig": true, "nslookup": true, "host": true, "wget": true,
+	"nc": true, "netcat": true, "netstat": true, "ss": true, "ifconfig": true, "ip": true, "telnet": true,
+	"ftp": true, "mosh": true, "ssh-keygen": true, "ssh-add": true, "ssh-copy-id": true, "ssh-agent": true,
+	"openssl": true, "gpg": true, "nmap": true, "tcpdump": true, "http": true, "httpie": true, "iptables": true,
+	"ufw": true, "whois": true, "ngrok": true, "tailscale": true, "wg": true,
+
+	// Version control
+	"svn": true, "hg": true, "tig": true, "lazygit": true, "git-lfs": true, "glab": true, "hub": true,
+
+	// Languages and build tools
+	"python2": true, "pip2": true, "ipython": true, "jupyter": true, "conda": true, "mamba": true,
+	"poetry": true, "pipenv": true, "pyenv": true, "virtualenv": true, "pytest": true, "tox": true,
+	"black": true, "flake8": true, "mypy": true, "ruff": true, "uv": true, "pipx": true,
+	"npx": true, "nvm": true, "deno": true, "bun": true, "tsc": true, "eslint": true, "prettier": true,
+	"webpack": true, "vite": true, "jest": true, "ts-node": true, "corepack": true, "n": true,
+	"ruby": true, "gem": true, "bundle": true, "bundler": true, "rake": true, "rails": true, "rbenv": true,
+	"rvm": true, "irb": true, "rspec": true, "php": true, "composer": true, "artisan": true, "perl": true,
+	"cpan": true, "lua": true, "luarocks": true, "rustc": true, "gofmt": true, "golangci-lint": true,
+	"dlv": true, "javac": true, "mvn": true, "gradle": true, "gradlew": true, "ant": true, "sbt": true,
+	"scala": true, "kotlin": true, "kotlinc": true, "dotnet": true, "swift": true, "swiftc": true,
+	"xcodebuild": true, "pod": true, "carthage": true, "flutter": true, "dart": true, "elixir": true,
+	"mix": true, "iex": true, "erl": true, "ghc": true, "ghci": true, "stack": true, "cabal": true,
+	"ocaml": true, "opam": true, "dune": true, "zig": true, "nim": true, "julia": true, "R": true,
+	"Rscript": true, "cmake": true, "ninja": true, "bazel": true, "buck": true, "meson": true, "ld": true,
+	"ar": true, "nm": true, "objdump": true, "otool": true, "ldd": true, "strip": true, "pkg-config": true,
+	"autoconf": true, "automake": true, "configure": true, "asdf": true, "mise": true, "direnv": true,
+	"sqlite3": true, "redis-cli": true, "mongo": true, "mongosh": true, "createdb": true, "dropdb": true,
+	"pg_ctl": true, "influx": true,
+
+	// Containers and cloud
+	"podman": true, "minikube": true, "kind": true, "k9s": true, "kubectx": true, "kubens": true,
+	"eksctl": true, "az": true, "doctl": true, "heroku": true, "vercel": true, "netlify": true,
+	"fly": true, "flyctl": true, "firebase": true, "gsutil": true, "bq": true, "pulumi": true,
+	"ansible": true, "ansible-playbook": true, "vagrant": true, "packer": true, "vault": true,
+	"consul": true, "nomad": true, "skaffold": true, "tilt": true, "kustomize": true, "istioctl": true,
+	"argocd": true, "stern": true, "colima": true, "lima": true, "multipass": true, "wrangler": true,
+	"sam": true, "serverless": true, "sls": true, "cdk": true, "terragrunt": true, "tofu": true,
+
+	// PowerShell
+	"Get-ChildItem": true, "Set-Location": true, "Get-Content": true, "Set-Content": true,
+	"Add-Content": true, "Get-Item": true, "New-Item": true, "Remove-Item": true, "Copy-Item": true,
+	"Move-Item": true, "Rename-Item": true, "Get-Process": true, "Stop-Process": true, "Start-Process": true,
+	"Get-Service": true, "Start-Service": true, "Stop-Service": true, "Restart-Service": true,
+	"Get-Command": true, "Get-Help": true, "Get-Member": true, "Select-Object": true, "Where-Object": true,
+	"ForEach-Object": true, "Sort-Object": true, "Measure-Object": true, "Format-Table": true,
+	"Format-List": true, "Out-File": true, "Out-Host": true, "Write-Host": true, "Write-Output": true,
+	"Select-String": true, "Invoke-WebRequest": true, "Invoke-RestMethod": true, "Invoke-Expression": true,
+	"Get-Location": true, "Test-Path": true, "Resolve-Path": true, "Join-Path": true, "Split-Path": true,
+	"Import-Module": true, "Install-Module": true, "Get-Module": true, "Set-ExecutionPolicy": true,
+	"Get-History": true, "Clear-Host": true, "Get-Date": true, "ConvertTo-Json": true,
+	"ConvertFrom-Json": true, "Enter-PSSession": true, "Get-Variable": true, "Set-Variable": true,
+	"Get-Alias": true, "Set-Alias": true, "cls": true, "dir": true, "gci": true, "sl": true, "gc": true,
+	"iwr": true, "irm": true, "del": true, "copy": true, "move": true, "ren": true, "md": true,
+
+	// nu
+	"each": true, "select": true, "get": true, "save": true, "to": true, "from": true, "str": true,
+	"let-env": true, "def": true, "mut": true, "sys": true, "reject": true, "sort-by": true,
+	"group-by": true, "length": true, "first": true, "last": true, "skip": true, "append": true,
+	"prepend": true, "upsert": true, "update": true, "insert": true, "describe": true, "table": true,
+	"config": true, "overlay": true, "use": true, "module": true, "par-each": true, "reduce": true,
+	"filter": true, "flatten": true, "transpose": true, "uniq-by": true, "into": true, "math": true,
+	"path": true, "parse": true, "lines": true, "split-by": true, "wrap": true, "do": true,
+}
+
+// normalizeCommand returns the name of a command as we upload it. Well known commands
+// keep their names, even when run by path as in /usr/bin/git or ./gradlew. Others are replaced by
+// a placeholder for how they were run, so paths and the names of personal scripts
+// aren't uploaded: <relative-script> for ./deploy.sh, <absolute-path:bin> for
+// ~/bin/deploy or /opt/acme/bin/deploy (keeping the directory only if it is a common
+// one), and <custom> or <custom:.sh> for anything else.
+func normalizeCommand(command string) string {
+	name := path.Base(command)
+	if isKnownCommand(name) {
+		return canonicalCommandName(name)
+	}
+	if !strings.Contains(command, "/") {
+		if isKnownCommand(command) {
+			return canonicalCommandName(command)
+		}
+		return withDetail(customCommand, scriptExtension(command))
+	}
+	if strings.HasPrefix(command, "/") || strings.HasPrefix(command, "~") {
+		return withDetail(absolutePathCommand, commonParentDir(command))
+	}
+	return relativeScriptCommand
+}
+
+// knownCommandsByLowerName maps the lowercased name of each well known command to
+// its usual spelling, as PowerShell ignores case, e.g. get-childitem is Get-ChildItem
+var knownCommandsByLowerName = lowerCommandNames(knownCommands)
+
+func lowerCommandNames(commands map[string]bool) map[string]string {
+	names := make(map[string]string, len(commands))
+	for name := range commands {
+		names[strings.ToLower(name)] = name
+	}
+	return names
+}
+
+// canonicalCommandName returns the usual spelling of a well known command, or the
+// name as it is if it isn't one
+func canonicalCommandName(name string) string {
+	if knownCommands[name] {
+		return name
+	}
+	if canonical, ok := knownCommandsByLowerName[strings.ToLower(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+func isKnownCommand(name string) bool {
+	if _, ok := knownCommandsByLowerName[strings.ToLower(name)]; ok {
+		return true
+	}
+	if _, ok := subcommandRegistry[name]; ok {
+		return true
+	}
+	if _, ok := commandWrappers[name]; ok {
+		return true
+	}
+	_, ok := flagGlueRules[name]
+	return ok
+}
+
+// Common directories for executables, which are safe to say a command was in
+var commonExecutableDirs = map[string]bool{
+	"bin":       true,
+	"sbin":      true,
+	"libexec":   true,
+	"scripts":   true,
+	".bin":      true,
+	"MacOS":     true,
+	"Resources": true,
+}
+
+func commonParentDir(command string) string {
+	parent := path.Base(path.Dir(command))
+	if commonExecutableDirs[parent] {
+		return parent
+	}
+	return ""
+}
+
+// Extensions of scripts and executables, which are safe to say a command had
+var scriptExtensions = map[string]bool{
+	".sh": true, ".bash": true, ".zsh": true, ".fish": true, ".nu": true, ".ps1": true, ".py": true,
+	".rb": true, ".pl": true, ".js": true, ".mjs": true, ".ts": true, ".php": true, ".lua": true,
+	".exe": true, ".bat": true, ".cmd": true, ".jar": true, ".AppImage": true, ".command": true,
+}
+
+func scriptExtension(command string) string {
+	extension := path.Ext(command)
+	if scriptExtensions[extension] {
+		return extension
+	}
+	return ""
+}
+
+// withDetail adds a detail to a placeholder, e.g. <custom:.sh>
+func withDetail(placeholder string, detail string) string {
+	if len(detail) == 0 {
+		return placeholder
+	}
+	return strings.TrimSuffix(placeholder, ">") + ":" + detail + ">"
+}
@@ -0,0 +1,40 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestNormalizeCommand(t *testing.T) {
+	assert.Equal(t, "ls", normalizeCommand("ls"))
+	assert.Equal(t, "git", normalizeCommand("/usr/local/bin/git"))
+	assert.Equal(t, "gradlew", normalizeCommand("./gradlew"))
+	assert.Equal(t, "kubectl", normalizeCommand("kubectl"))
+	assert.Equal(t, "Get-ChildItem", normalizeCommand("Get-ChildItem"))
+	assert.Equal(t, "Get-ChildItem", normalizeCommand("get-childitem"))
+	assert.Equal(t, "Get-ChildItem", normalizeCommand("GET-CHILDITEM"))
+	assert.Equal(t, "Where-Object", normalizeCommand("where-object"))
+
+	assert.Equal(t, "<relative-script>", normalizeCommand("./scripts/acme-payroll-migrate.sh"))
+	assert.Equal(t, "<relative-script>", normalizeCommand("bin/setup"))
+	assert.Equal(t, "<absolute-path:bin>", normalizeCommand("/home/jdoe/bin/deploy-prod"))
+	assert.Equal(t, "<absolute-path:bin>", normalizeCommand("~/bin/deploy-prod"))
+	assert.Equal(t, "<absolute-path>", normalizeCommand("/opt/acme/payroll/run"))
+	assert.Equal(t, "<custom>", normalizeCommand("acme-deploy"))
+	assert.Equal(t, "<custom:.sh>", normalizeCommand("acme-deploy.sh"))
+	assert.Equal(t, "<custom>", normalizeCommand("acme.internal"))
+}
+
+func TestRedactCommandNormalizesCommand(t *testing.T) {
+	r := RedactCommand(shell.Bash, []string{"/usr/bin/git status"})
+	assert.Equal(t, "git", r.Command)
+	assert.Equal(t, "status", r.Subcommand)
+
+	r = RedactCommand(shell.Bash, []string{"sudo ./scripts/acme-payroll-migrate.sh --dry-run"})
+	assert.Equal(t, "<relative-script>", r.Command)
+	assert.Equal(t, []string{"sudo"}, r.Wrappers)
+	assert.Equal(t, []string{"dry-run"}, r.Options)
+	assert.NotContains(t, r.Preview(), "acme")
+}
@@ -0,0 +1,237 @@
+package history
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// HistoryCandidate is a history file found on the machine
+type HistoryCandidate struct {
+	Path      string
+	ShellType shell.Type
+
+	// Source is how the file was found, e.g. HISTFILE in ~/.zshrc
+	Source string
+
+	Size    int64
+	ModTime time.Time
+
+	// Sessions is set if Path is a directory with a history file per terminal
+	// session, see RedactHistoryDir. Size is then the size of all of them.
+	Sessions bool
+}
+
+// Matches lines like export HISTFILE="$HOME/.history" in bash and zsh startup files
+var histFileRegEx = regexp.MustCompile(`^\s*(?:export\s+|typeset\s+(?:-x\s+)?|declare\s+(?:-x\s+)?)?HISTFILE=(\S+)`)
+
+// Matches lines like set -U fish_history work in config.fish, which make fish keep
+// history in work_history
+var fishHistoryRegEx = regexp.MustCompile(`^\s*set\s+(?:-[a-zA-Z]+\s+)*fish_history\s+(\S+)`)
+
+// DiscoverHistoryFiles returns every non-empty history file we can find for any shell,
+// most likely first: $HISTFILE, files set as HISTFILE in shell startup files, each
+// shell's default locations, macOS Terminal's sessions directories, and anything in the
+// home directory named after a shell's history.
+func DiscoverHistoryFiles() []HistoryCandidate {
+	return discoverHistoryFiles(os.Getenv)
+}
+
+// discoverHistoryFiles finds history files with the environment given by getenv
+func discoverHistoryFiles(getenv func(string) string) []HistoryCandidate {
+	d := &discovery{getenv: getenv, home: getenv("HOME"), seen: map[string]bool{}}
+	zdotdir := d.expand("${ZDOTDIR:-$HOME}")
+	dataHome := d.expand("${XDG_DATA_HOME:-$HOME/.local/share}")
+	configHome := d.expand("${XDG_CONFIG_HOME:-$HOME/.config}")
+
+	if histFile := getenv("HISTFILE"); len(histFile) > 0 {
+		path := d.resolve(histFile)
+		shellType, _ := shell.DetectShellType(path)
+		if shellType == shell.Unknown {
+			// Most likely the history of the shell the user runs
+			shellType = shell.GetShellType(filepath.Base(getenv("SHELL")))
+		}
+		d.add(path, shellType, "$HISTFILE")
+	}
+
+	for _, rc := range []string{".bashrc", ".bash_profile", ".bash_login", ".profile"} {
+		d.addFromStartupFile(filepath.Join(d.home, rc), shell.Bash, histFileRegEx, "")
+	}
+	for _, rc := range []string{".zshenv", ".zprofile", ".zshrc", ".zlogin"} {
+		d.addFromStartupFile(filepath.Join(zdotdir, rc), shell.Zsh, histFileRegEx, "")
+	}
+	d.addFromStartupFile(filepath.Join(configHome, "fish", "config.fish"), shell.Fish, fishHistoryRegEx,
+		filepath.Join(dataHome, "fish")+"/%s_history")
+
+	d.add(filepath.Join(d.home, ".bash_history"), shell.Bash, "default bash history")
+	for _, dir := range []string{zdotdir, d.home} {
+		for _, name := range []string{".zsh_history", ".zhistory", ".histfile"} {
+			d.add(filepath.Join(dir, name), shell.Zsh, "default zsh history")
+		}
+	}
+	d.addSessions(filepath.Join(d.home, ".bash_sessions"), shell.Bash, "macOS Terminal bash sessions")
+	d.addSessions(filepath.Join(zdotdir, ".zsh_sessions"), shell.Zsh, "macOS Terminal zsh sessions")
+	d.addMatching(filepath.Join(dataHome, "fish", "*_history"), shell.Fish, "fish history directory")
+
+	psReadLineDirs := []string{filepath.Join(dataHome, "powershell", "PSReadLine")}
+	if appData := getenv("APPDATA"); len(appData) > 0 {
+		psReadLineDirs = append(psReadLineDirs, filepath.Join(appData, "Microsoft", "Windows", "PowerShell", "PSReadLine"))
+	}
+	for _, dir := range psReadLineDirs {
+		d.addMatching(filepath.Join(dir, "*_history.txt"), shell.PowerShell, "PSReadLine history directory")
+	}
+
+	nuDirs := []string{
+		filepath.Join(configHome, "nushell"),
+		filepath.Join(d.home, "Library", "Application Support", "nushell"),
+	}
+	for _, dir := range nuDirs {
+		d.add(filepath.Join(dir, "history.txt"), shell.Nu, "nu history")
+		if sqliteAvailable() {
+			d.add(filepath.Join(dir, "history.sqlite3"), shell.Nu, "nu history")
+		}
+	}
+
+	if entries, err := ioutil.ReadDir(d.home); err == nil {
+		for _, entry := range entries {
+			shellType := shell.GetShellType(entry.Name())
+			if strings.Contains(entry.Name(), "history") && shellType != shell.Unknown {
+				d.add(filepath.Join(d.home, entry.Name()), shellType, "home directory")
+			}
+		}
+	}
+	return d.candidates
+}
+
+// discovery collects history files, skipping any already found by another route
+type discovery struct {
+	getenv     func(string) string
+	home       string
+	seen       map[string]bool
+	candidates []HistoryCandidate
+}
+
+// add adds a history file if it exists, isn't empty, and hasn't already been found
+func (d *discovery) add(path string, shellType shell.Type, source string) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return
+	}
+	key := filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		key = resolved
+	}
+	if d.seen[key] {
+		return
+	}
+	d.seen[key] = true
+	d.candidates = append(d.candidates, HistoryCandidate{
+		Path:      path,
+		ShellType: shellType,
+		Source:    source,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+	})
+}
+
+// addSessions adds a directory of session history files if it has any that aren't
+// empty
+func (d *discovery) addSessions(dir string, shellType shell.Type, source string) {
+	files, err := sessionHistoryFiles(dir)
+	if err != nil || len(files) == 0 {
+		return
+	}
+	key := filepath.Clean(dir)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		key = resolved
+	}
+	if d.seen[key] {
+		return
+	}
+	d.seen[key] = true
+
+	candidate := HistoryCandidate{Path: dir, ShellType: shellType, Source: source, Sessions: true}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			candidate.Size += info.Size()
+			if info.ModTime().After(candidate.ModTime) {
+				candidate.ModTime = info.ModTime()
+			}
+		}
+	}
+	d.candidates = append(d.candidates, candidate)
+}
+
+// addMatching adds every history file matching a glob pattern
+func (d *discovery) addMatching(pattern string, shellType shell.Type, source string) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return
+	}
+	for _, match := range matches {
+		d.add(match, shellType, source)
+	}
+}
+
+// addFromStartupFile adds the history files a shell startup file sets with lines
+// matching regEx. If pathFormat is set, the value is a name to substitute into it
+// rather than a path.
+func (d *discovery) addFromStartupFile(rcPath string, shellType shell.Type, regEx *regexp.Regexp, pathFormat string) {
+	rcFile, err := os.Open(rcPath)
+	if err != nil {
+		return
+	}
+	defer rcFile.Close()
+
+	source := strings.Replace(rcPath, d.home, "~", 1)
+	scanner := bufio.NewScanner(rcFile)
+	for scanner.Scan() {
+		match := regEx.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		value := strings.Trim(match[1], `"'`)
+		if len(pathFormat) > 0 {
+			d.add(strings.Replace(pathFormat, "%s", value, 1), shellType, "fish_history in "+source)
+		} else {
+			d.add(d.resolve(value), shellType, "HISTFILE in "+source)
+		}
+	}
+}
+
+// resolve expands variables and ~ in a history file setting, relative to the home
+// directory if it isn't absolute
+func (d *discovery) resolve(path string) string {
+	path = d.expand(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = d.home + path[1:]
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(d.home, path)
+	}
+	return path
+}
+
+// expand expands $VAR, ${VAR} and ${VAR:-default} in a path
+func (d *discovery) expand(path string) string {
+	return os.Expand(path, func(name string) string {
+		fallback := ""
+		if i := strings.Index(name, ":-"); i >= 0 {
+			name, fallback = name[:i], d.expand(name[i+2:])
+		}
+		value := d.getenv(name)
+		if name == "HOME" {
+			value = d.home
+		}
+		if len(value) == 0 {
+			return fallback
+		}
+		return value
+	})
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func writeTestFile(t *testing.T, path string, contents string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestDiscoverHistoryFiles(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+
+	writeTestFile(t, filepath.Join(home, ".bash_history"), "ls\n")
+	writeTestFile(t, filepath.Join(home, ".zshrc"), "setopt share_history\nexport HISTFILE=\"$HOME/.histories/zsh\"\n")
+	writeTestFile(t, filepath.Join(home, ".histories", "zsh"), ": 1584112360:0;ls\n")
+	writeTestFile(t, filepath.Join(home, ".config", "fish", "config.fish"), "set -U fish_history work\n")
+	writeTestFile(t, filepath.Join(home, ".local", "share", "fish", "work_history"), "- cmd: ls\n")
+	writeTestFile(t, filepath.Join(home, ".local", "share", "fish", "fish_history"), "- cmd: pwd\n")
+	writeTestFile(t, filepath.Join(home, ".zsh_history"), "")
+	writeTestFile(t, filepath.Join(home, ".bash_history.old"), "pwd\n")
+	assert.Nil(t, os.Symlink(filepath.Join(home, ".bash_history"), filepath.Join(home, "bash_history_link")))
+
+	env := map[string]string{"HOME": home}
+	candidates := discoverHistoryFiles(func(name string) string { return env[name] })
+
+	found := make([]string, 0)
+	for _, candidate := range candidates {
+		rel, _ := filepath.Rel(home, candidate.Path)
+		found = append(found, rel+" "+string(candidate.ShellType)+" "+candidate.Source)
+	}
+	assert.Equal(t, []string{
+		".histories/zsh Zsh HISTFILE in ~/.zshrc",
+		".local/share/fish/work_history Fish fish_history in ~/.config/fish/config.fish",
+		".bash_history Bash default bash history",
+		".local/share/fish/fish_history Fish fish history directory",
+		".bash_history.old Bash home directory",
+	}, found)
+}
+
+func TestDiscoverHistoryFilesFromEnvironment(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+
+	writeTestFile(t, filepath.Join(home, "zdot", ".zshrc"), "HISTFILE=${ZDOTDIR:-$HOME}/.zsh_history\n")
+	writeTestFile(t, filepath.Join(home, "zdot", ".zsh_history"), ": 1584112360:0;ls\n")
+	writeTestFile(t, filepath.Join(home, "hist"), ": 1584112360:0;ls\n")
+
+	env := map[string]string{"HOME": home, "ZDOTDIR": filepath.Join(home, "zdot"), "HISTFILE": "~/hist"}
+	candidates := discoverHistoryFiles(func(name string) string { return env[name] })
+	assert.Equal(t, 2, len(candidates))
+	assert.Equal(t, filepath.Join(home, "hist"), candidates[0].Path)
+	assert.Equal(t, shell.Type(shell.Zsh), candidates[0].ShellType)
+	assert.Equal(t, "$HISTFILE", candidates[0].Source)
+	assert.Equal(t, filepath.Join(home, "zdot", ".zsh_history"), candidates[1].Path)
+	assert.Equal(t, "HISTFILE in ~/zdot/.zshrc", candidates[1].Source)
+
+	// A $HISTFILE with nothing to say which shell wrote it is taken to be $SHELL's
+	writeTestFile(t, filepath.Join(home, "hist"), "ls\n")
+	env["SHELL"] = "/bin/zsh"
+	candidates = discoverHistoryFiles(func(name string) string { return env[name] })
+	assert.Equal(t, shell.Type(shell.Zsh), candidates[0].ShellType)
+}
+
+func TestDiscoverSessionDirectories(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+
+	writeTestFile(t, filepath.Join(home, ".bash_sessions", "A.history"), "ls\n")
+	writeTestFile(t, filepath.Join(home, ".bash_sessions", "B.history"), "make\n")
+	writeTestFile(t, filepath.Join(home, ".zsh_sessions", "A.session"), "echo Restored session\n")
+
+	env := map[string]string{"HOME": home}
+	candidates := discoverHistoryFiles(func(name string) string { return env[name] })
+	assert.Equal(t, 1, len(candidates))
+	assert.Equal(t, filepath.Join(home, ".bash_sessions"), candidates[0].Path)
+	assert.Equal(t, shell.Type(shell.Bash), candidates[0].ShellType)
+	assert.True(t, candidates[0].Sessions)
+	assert.Equal(t, int64(8), candidates[0].Size)
+
+	history := RedactHistoryCandidate(candidates[0])
+	assert.NotNil(t, history)
+	assert.Equal(t, 2, len(history.RedactedLines))
+}
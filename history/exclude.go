@@ -0,0 +1,97 @@
+package history
+
+import (
+	"regexp"
+)
+
+// exclusion is a set of commands the user left out of their history, with the
+// indexes they had in RedactedLines so they can be put back
+type exclusion struct {
+	indexes  []int
+	commands []*RedactedCommand
+}
+
+// ExcludeRange leaves the commands from start up to but not including end out of the
+// history, returning how many were left out. It can be undone with Undo.
+func (h *ShellHistory) ExcludeRange(start int, end int) int {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(h.RedactedLines) {
+		end = len(h.RedactedLines)
+	}
+	return h.exclude(func(i int, _ *RedactedCommand) bool { return i >= start && i < end })
+}
+
+// ExcludeCommand leaves every use of a command out of the history, returning how many
+// were left out. It can be undone with Undo.
+func (h *ShellHistory) ExcludeCommand(command string) int {
+	return h.exclude(func(_ int, r *RedactedCommand) bool { return r != nil && r.Command == command })
+}
+
+// Undo puts back the commands left out by the last call to ExcludeRange or
+// ExcludeCommand that hasn't been undone, returning how many were put back
+func (h *ShellHistory) Undo() int {
+	if len(h.exclusions) == 0 {
+		return 0
+	}
+	last := h.exclusions[len(h.exclusions)-1]
+	h.exclusions = h.exclusions[:len(h.exclusions)-1]
+
+	lines := make([]*RedactedCommand, 0, len(h.RedactedLines)+len(last.commands))
+	next := 0
+	for i, index := range last.indexes {
+		for len(lines) < index && next < len(h.RedactedLines) {
+			lines = append(lines, h.RedactedLines[next])
+			next++
+		}
+		lines = append(lines, last.commands[i])
+	}
+	h.RedactedLines = append(lines, h.RedactedLines[next:]...)
+	return len(last.commands)
+}
+
+// Excluded returns how many commands the user has left out of the history
+func (h *ShellHistory) Excluded() int {
+	excluded := 0
+	for _, e := range h.exclusions {
+		excluded += len(e.commands)
+	}
+	return excluded
+}
+
+// Search returns the indexes of the commands whose original text or redacted preview
+// match a regular expression, or contain the pattern if it isn't a valid one
+func (h *ShellHistory) Search(pattern string) []int {
+	regEx, err := regexp.Compile(pattern)
+	if err != nil {
+		regEx = regexp.MustCompile(regexp.QuoteMeta(pattern))
+	}
+	matches := make([]int, 0)
+	for i, r := range h.RedactedLines {
+		if r != nil && (regEx.MatchString(r.source) || regEx.MatchString(r.Preview())) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// exclude removes the commands matching excluded, remembering them for Undo
+func (h *ShellHistory) exclude(excluded func(i int, r *RedactedCommand) bool) int {
+	removed := exclusion{indexes: make([]int, 0), commands: make([]*RedactedCommand, 0)}
+	kept := make([]*RedactedCommand, 0, len(h.RedactedLines))
+	for i, r := range h.RedactedLines {
+		if excluded(i, r) {
+			removed.indexes = append(removed.indexes, i)
+			removed.commands = append(removed.commands, r)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	if len(removed.commands) == 0 {
+		return 0
+	}
+	h.RedactedLines = kept
+	h.exclusions = append(h.exclusions, removed)
+	return len(removed.commands)
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func excludeTestHistory() *ShellHistory {
+	h := newShellHistory(".bash_history", shell.Bash)
+	for _, line := range []string{"ls", "ssh prod-db", "git status", "ls -la", "ssh bastion", "make"} {
+		h.redactEntry(shell.Bash, []string{line})
+	}
+	return h
+}
+
+func commandNames(h *ShellHistory) []string {
+	names := make([]string, 0)
+	for _, r := range h.RedactedLines {
+		names = append(names, r.Command)
+	}
+	return names
+}
+
+func TestExcludeAndUndo(t *testing.T) {
+	h := excludeTestHistory()
+	assert.Equal(t, 2, h.ExcludeCommand("ssh"))
+	assert.Equal(t, []string{"ls", "git", "ls", "make"}, commandNames(h))
+
+	assert.Equal(t, 2, h.ExcludeRange(1, 3))
+	assert.Equal(t, []string{"ls", "make"}, commandNames(h))
+	assert.Equal(t, 4, h.Excluded())
+
+	assert.Equal(t, 2, h.Undo())
+	assert.Equal(t, []string{"ls", "git", "ls", "make"}, commandNames(h))
+	assert.Equal(t, 2, h.Undo())
+	assert.Equal(t, []string{"ls", "ssh", "git", "ls", "ssh", "make"}, commandNames(h))
+	assert.Equal(t, 0, h.Undo())
+	assert.Equal(t, 0, h.Excluded())
+}
+
+func TestExcludeRangeOutOfBounds(t *testing.T) {
+	h := excludeTestHistory()
+	assert.Equal(t, 2, h.ExcludeRange(4, 100))
+	assert.Equal(t, 0, h.ExcludeRange(10, 20))
+	assert.Equal(t, []string{"ls", "ssh", "git", "ls"}, commandNames(h))
+}
+
+func TestSearch(t *testing.T) {
+	h := excludeTestHistory()
+	assert.Equal(t, []int{1}, h.Search("prod"))
+	assert.Equal(t, []int{1, 4}, h.Search("^ssh"))
+	assert.Equal(t, []int{3}, h.Search("flags: la"))
+	assert.Equal(t, []int{}, h.Search("("))
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fish format
+// - cmd: <cmd>
+//   when: <timestamp>
+//   paths:
+//     - <path>
+//
+// Fish escapes backslashes as \\ and newlines as \n inside <cmd>.
+
+const (
+	fishCmdPrefix  = "- cmd:"
+	fishWhenPrefix = "when:"
+)
+
+// readFishHistory splits a fish history file into entries, one per command.
+// Each entry holds the "- cmd:" line followed by any indented lines
+// (when, paths) that belong to it. Returns the number of lines that appear
+// before the first entry.
+func readFishHistory(reader *bufio.Reader, emit func(entry []string)) (int, error) {
+	unattributed := 0
+	var entry []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, fishCmdPrefix) {
+			if entry != nil {
+				emit(entry)
+			}
+			entry = []string{line}
+		} else if len(strings.TrimSpace(line)) > 0 {
+			if entry != nil {
+				entry = append(entry, line)
+			} else {
+				unattributed++
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if entry != nil {
+		emit(entry)
+	}
+	return unattributed, nil
+}
+
+// parseFishLines returns the command and timestamp, if any, from the lines
+// of a single fish history entry.
+func parseFishLines(lines []string) (commandTime time.Time, command string) {
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, fishCmdPrefix) {
+			command = unescapeFish(strings.TrimSpace(trimmed[len(fishCmdPrefix):]))
+		} else if strings.HasPrefix(trimmed, fishWhenPrefix) {
+			timestampSecs, err := strconv.Atoi(strings.TrimSpace(trimmed[len(fishWhenPrefix):]))
+			if err == nil {
+				commandTime = time.Unix(int64(timestampSecs), 0)
+			}
+		}
+	}
+	return
+}
+
+// unescapeFish reverses the escaping fish applies to commands in its history
+// file: \\ becomes a backslash and \n becomes a newline. Any other backslash
+// sequence is left untouched.
+func unescapeFish(escaped string) string {
+	var b strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		c := escaped[i]
+		if c == '\\' && i+1 < len(escaped) {
+			switch escaped[i+1] {
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
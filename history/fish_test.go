@@ -0,0 +1,51 @@
+package history
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+const fishHistory = `- cmd: git status
+  when: 1591025337
+- cmd: cd ~/src
+  when: 1591025340
+  paths:
+    - ~/src
+- cmd: echo foo\\nbar
+  when: 1591025345
+- cmd: for f in *\n  echo $f\nend
+  when: 1591025350
+`
+
+func TestReadFishHistory(t *testing.T) {
+	entries, unattributed, err := readAllEntries(readFishHistory, bufio.NewReader(strings.NewReader(fishHistory)))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(entries))
+	assert.Equal(t, 0, unattributed)
+	assert.Equal(t, 4, len(entries[1]))
+}
+
+func TestRedactCommandFish(t *testing.T) {
+	r := RedactCommand(shell.Fish, []string{"- cmd: git commit -m 'wip'", "  when: 1591025337"})
+	assert.Equal(t, "git", r.Command)
+	assert.Equal(t, "commit", r.Subcommand)
+	assert.Equal(t, "m", r.Options[0])
+	assert.Equal(t, time.Unix(1591025337, 0), r.Timestamp)
+}
+
+func TestParseFishLinesPaths(t *testing.T) {
+	ts, cmd := parseFishLines([]string{"- cmd: cd ~/src", "  when: 1591025340", "  paths:", "    - ~/src"})
+	assert.Equal(t, "cd ~/src", cmd)
+	assert.Equal(t, time.Unix(1591025340, 0), ts)
+}
+
+func TestUnescapeFish(t *testing.T) {
+	assert.Equal(t, `echo foo\nbar`, unescapeFish(`echo foo\\nbar`))
+	assert.Equal(t, "for f in *\n  echo $f\nend", unescapeFish(`for f in *\n  echo $f\nend`))
+	assert.Equal(t, `echo \t`, unescapeFish(`echo \t`))
+}
@@ -0,0 +1,317 @@
+package history
+
+import (
+	"sort"
+	"strings"
+)
+
+// flagGlueRule describes the short options of a command that can have their value
+// glued on, like mysql -pHunter2, gcc -I/usr/include or java -Xmx4g, so we can keep
+// the flag and drop the value. Flags are recorded by their canonical (long) names.
+type flagGlueRule struct {
+	// valueFlags are short flags that take a value, either the rest of the word as in
+	// -I/usr/include or the next word
+	valueFlags map[byte]string
+
+	// boolFlags are short flags that don't take a value and can be clustered with
+	// others, as in tar -xzf
+	boolFlags map[byte]string
+
+	// prefixFlags are single dash options longer than one letter, with any value
+	// glued on, like -Xmx in java -Xmx4g. The longest match wins.
+	prefixFlags map[string]string
+}
+
+var mysqlFlags = flagGlueRule{
+	valueFlags: map[byte]string{'u': "user", 'p': "password", 'h': "host", 'P': "port", 'D': "database",
+		'e': "execute", 'S': "socket", 'r': "result-file"},
+	boolFlags: map[byte]string{'v': "verbose", 's': "silent", 'N': "skip-column-names", 'B': "batch",
+		't': "table", 'E': "vertical", 'f': "force", 'A': "no-auto-rehash", 'X': "xml", 'H': "html"},
+}
+
+var psqlFlags = flagGlueRule{
+	valueFlags: map[byte]string{'U': "username", 'h': "host", 'p': "port", 'd': "dbname", 'c': "command",
+		'f': "file", 'o': "output", 'F': "field-separator", 'v': "variable", 'L': "log-file", 'P': "pset",
+		'R': "record-separator", 'T': "table-attr"},
+	boolFlags: map[byte]string{'W': "password", 'w': "no-password", 'q': "quiet", 'a': "echo-all",
+		'e': "echo-queries", 'E': "echo-hidden", 'b': "echo-errors", 'x': "expanded", 'A': "no-align",
+		'l': "list", 'X': "no-psqlrc", 't': "tuples-only", 'H': "html", 'n': "no-readline", 's': "single-step",
+		'S': "single-line", '1': "single-transaction", 'z': "field-separator-zero", '0': "record-separator-zero",
+		'V': "version"},
+}
+
+var pgDumpFlags = flagGlueRule{
+	valueFlags: map[byte]string{'U': "username", 'h': "host", 'p': "port", 'd': "dbname", 'f': "file",
+		'F': "format", 't': "table", 'n': "schema", 'T': "exclude-table", 'N': "exclude-schema", 'j': "jobs",
+		'Z': "compress", 'E': "encoding", 'S': "superuser", 'L': "use-list", 'I': "index", 'P': "function"},
+	boolFlags: map[byte]string{'W': "password", 'w': "no-password", 'c': "clean", 'C': "create",
+		's': "schema-only", 'a': "data-only", 'O': "no-owner", 'x': "no-privileges", 'v': "verbose",
+		'b': "blobs", 'e': "exit-on-error", '1': "single-transaction", 'l': "list", 'V': "version"},
+}
+
+var compilerFlags = flagGlueRule{
+	valueFlags: map[byte]string{'I': "include-dir", 'D': "define", 'U': "undefine", 'L': "library-dir",
+		'l': "library", 'o': "output", 'x': "language", 'O': "optimize", 'W': "warning", 'f': "feature",
+		'm': "machine", 'B': "prefix"},
+	boolFlags: map[byte]string{'c': "compile-only", 'S': "assemble-only", 'E': "preprocess-only",
+		'g': "debug", 'v': "verbose", 'w': "no-warnings", 'M': "dependencies", 'P': "no-line-markers",
+		's': "strip"},
+	prefixFlags: map[string]string{"std": "std", "shared": "shared", "static": "static", "pthread": "pthread",
+		"pedantic": "pedantic", "rdynamic": "rdynamic", "isystem": "isystem", "include": "include",
+		"march": "march", "mtune": "mtune", "MD": "dependencies", "MMD": "dependencies", "MF": "dependency-file",
+		"Wl,": "linker-option", "Xlinker": "linker-option"},
+}
+
+var sshFlags = flagGlueRule{
+	valueFlags: map[byte]string{'i': "identity-file", 'p': "port", 'l': "login-name", 'o': "option",
+		'F': "config-file", 'L': "local-forward", 'R': "remote-forward", 'D': "dynamic-forward",
+		'J': "jump-host", 'b': "bind-address", 'c': "cipher", 'E': "log-file", 'm': "mac", 'S': "control-path",
+		'W': "stdio-forward", 'w': "tunnel", 'e': "escape-char", 'O': "control-command", 'Q': "query",
+		'B': "bind-interface", 'I': "pkcs11"},
+	boolFlags: map[byte]string{'v': "verbose", 'A': "forward-agent", 'a': "no-forward-agent", 'N': "no-command",
+		'T': "no-tty", 't': "tty", 'X': "x11", 'x': "no-x11", 'Y': "trusted-x11", 'q': "quiet", 'C': "compress",
+		'f': "background", '4': "ipv4", '6': "ipv6", 'g': "gateway-ports", 'K': "gssapi", 'k': "no-gssapi",
+		'M': "master", 'n': "no-stdin", 's': "subsystem", 'G': "print-config", 'V': "version"},
+}
+
+var scpFlags = flagGlueRule{
+	valueFlags: map[byte]string{'i': "identity-file", 'P': "port", 'o': "option", 'F': "config-file",
+		'l': "limit", 'c': "cipher", 'J': "jump-host", 'S': "program", 'D': "sftp-server"},
+	boolFlags: map[byte]string{'r': "recursive", 'p': "preserve", 'q': "quiet", 'v': "verbose",
+		'C': "compress", '3': "through-local", '4': "ipv4", '6': "ipv6", 'B': "batch", 'O': "legacy-protocol",
+		's': "sftp-protocol", 'A': "forward-agent", 'T': "no-strict-filename-checking"},
+}
+
+var tarFlags = flagGlueRule{
+	valueFlags: map[byte]string{'f': "file", 'C': "directory", 'T': "files-from", 'X': "exclude-from",
+		'b': "blocking-factor", 'I': "use-compress-program", 'N': "newer", 'L': "tape-length", 'H': "format"},
+	boolFlags: map[byte]string{'x': "extract", 'c': "create", 't': "list", 'z': "gzip", 'j': "bzip2",
+		'J': "xz", 'v': "verbose", 'p': "preserve-permissions", 'r': "append", 'u': "update",
+		'k': "keep-old-files", 'h': "dereference", 'P': "absolute-names", 'O': "to-stdout",
+		'a': "auto-compress", 'Z': "compress", 'm': "touch", 'W': "verify", 'A': "concatenate",
+		'd': "diff", 'S': "sparse", 'w': "interactive", 'U': "unlink-first", 'B': "read-full-records",
+		'i': "ignore-zeros", 'l': "check-links", 'M': "multi-volume", 'R': "block-number", 's': "same-order",
+		'G': "incremental", 'g': "listed-incremental", 'V': "label", 'o': "old-archive"},
+}
+
+var javaFlags = flagGlueRule{
+	prefixFlags: map[string]string{"Xmx": "max-heap-size", "Xms": "initial-heap-size", "Xss": "thread-stack-size",
+		"Xmn": "young-generation-size", "XX:": "vm-option", "D": "system-property", "agentlib:": "agentlib",
+		"agentpath:": "agentpath", "javaagent:": "javaagent", "cp": "classpath", "classpath": "classpath",
+		"jar": "jar", "ea": "enable-assertions", "da": "disable-assertions", "verbose": "verbose",
+		"version": "version", "server": "server", "client": "client", "Xdebug": "debug", "Xlog": "log",
+		"Xverify": "verify", "Xshare": "share", "Xbootclasspath": "bootclasspath", "splash:": "splash",
+		"showversion": "showversion", "X": "extra-option"},
+}
+
+var grepFlags = flagGlueRule{
+	valueFlags: map[byte]string{'e': "regexp", 'f': "file", 'm': "max-count", 'A': "after-context",
+		'B': "before-context", 'C': "context", 'd': "directories", 'D': "devices"},
+	boolFlags: map[byte]string{'i': "ignore-case", 'v': "invert-match", 'r': "recursive", 'R': "dereference-recursive",
+		'n': "line-number", 'l': "files-with-matches", 'L': "files-without-match", 'c': "count",
+		'o': "only-matching", 'q': "quiet", 's': "no-messages", 'w': "word-regexp", 'x': "line-regexp",
+		'E': "extended-regexp", 'F': "fixed-strings", 'G': "basic-regexp", 'P': "perl-regexp",
+		'H': "with-filename", 'h': "no-filename", 'I': "binary-without-match", 'a': "text", 'b': "byte-offset",
+		'U': "binary", 'z': "null-data", 'Z': "null", 'T': "initial-tab", 'y': "ignore-case", 'V': "version"},
+}
+
+var headTailFlags = flagGlueRule{
+	valueFlags: map[byte]string{'n': "lines", 'c': "bytes", 's': "sleep-interval"},
+	boolFlags:  map[byte]string{'f': "follow", 'F': "follow-retry", 'q': "quiet", 'v': "verbose", 'z': "zero-terminated"},
+}
+
+var makeFlags = flagGlueRule{
+	valueFlags: map[byte]string{'j': "jobs", 'C': "directory", 'f': "file", 'l': "load-average",
+		'I': "include-dir", 'o': "old-file", 'W': "what-if"},
+	boolFlags: map[byte]string{'k': "keep-going", 'n': "dry-run", 's': "silent", 'B': "always-make",
+		'i': "ignore-errors", 'e': "environment-overrides", 'd': "debug", 'p': "print-data-base", 'q': "question",
+		'r': "no-builtin-rules", 'R': "no-builtin-variables", 't': "touch", 'v': "version", 'w': "print-directory",
+		'S': "no-keep-going"},
+}
+
+var curlFlags = flagGlueRule{
+	valueFlags: map[byte]string{'u': "user", 'H': "header", 'd': "data", 'o': "output", 'X': "request",
+		'A': "user-agent", 'e': "referer", 'b': "cookie", 'c': "cookie-jar", 'T': "upload-file", 'x': "proxy",
+		'm': "max-time", 'F': "form", 'w': "write-out", 'K': "config", 'E': "cert", 'r': "range",
+		'C': "continue-at", 'U': "proxy-user", 'Y': "speed-limit", 'y': "speed-time", 'z': "time-cond",
+		'Q': "quote", 'P': "ftp-port", 't': "telnet-option", 'D': "dump-header"},
+	boolFlags: map[byte]string{'s': "silent", 'S': "show-error", 'L': "location", 'k': "insecure",
+		'v': "verbose", 'i': "include", 'I': "head", 'f': "fail", 'O': "remote-name", 'J': "remote-header-name",
+		'4': "ipv4", '6': "ipv6", 'N': "no-buffer", '#': "progress-bar", 'G': "get", 'g': "globoff",
+		'n': "netrc", 'R': "remote-time", 'Z': "parallel", 'a': "append", 'B': "use-ascii", 'j': "junk-session-cookies",
+		'l': "list-only", 'p': "proxytunnel", 'q': "disable", 'V': "version", '0': "http1.0", '1': "tlsv1",
+		'2': "sslv2", '3': "sslv3"},
+}
+
+var sedFlags = flagGlueRule{
+	valueFlags: map[byte]string{'e': "expression", 'f': "file", 'i': "in-place", 'l': "line-length"},
+	boolFlags: map[byte]string{'n': "quiet", 'E': "regexp-extended", 'r': "regexp-extended", 's': "separate",
+		'u': "unbuffered", 'z': "null-data"},
+}
+
+var awkFlags = flagGlueRule{
+	valueFlags: map[byte]string{'F': "field-separator", 'v': "assign", 'f': "file"},
+}
+
+var cutFlags = flagGlueRule{
+	valueFlags: map[byte]string{'d': "delimiter", 'f': "fields", 'c': "characters", 'b': "bytes"},
+	boolFlags:  map[byte]string{'s': "only-delimited", 'z': "zero-terminated", 'n': "n"},
+}
+
+var sortFlags = flagGlueRule{
+	valueFlags: map[byte]string{'k': "key", 't': "field-separator", 'o': "output", 'S': "buffer-size",
+		'T': "temporary-directory"},
+	boolFlags: map[byte]string{'n': "numeric-sort", 'r': "reverse", 'u': "unique", 'f': "ignore-case",
+		'h': "human-numeric-sort", 'V': "version-sort", 'b': "ignore-leading-blanks", 'd': "dictionary-order",
+		'g': "general-numeric-sort", 'M': "month-sort", 'R': "random-sort", 's': "stable", 'c': "check",
+		'm': "merge", 'z': "zero-terminated", 'i': "ignore-nonprinting"},
+}
+
+var pythonFlags = flagGlueRule{
+	valueFlags: map[byte]string{'c': "command", 'm': "module", 'W': "warning", 'X': "option"},
+	boolFlags: map[byte]string{'u': "unbuffered", 'i': "inspect", 'O': "optimize", 'B': "dont-write-bytecode",
+		'E': "ignore-environment", 's': "no-user-site", 'S': "no-site", 'v': "verbose", 'q': "quiet",
+		'V': "version", 'b': "bytes-warning", 'd': "debug", 'I': "isolated", 'P': "safe-path", 'x': "skip-first-line",
+		'h': "help"},
+}
+
+var nodeFlags = flagGlueRule{
+	valueFlags: map[byte]string{'e': "eval", 'p': "print", 'r': "require", 'C': "conditions"},
+	boolFlags:  map[byte]string{'i': "interactive", 'v': "version", 'h': "help", 'c': "check"},
+}
+
+var rsyncFlags = flagGlueRule{
+	valueFlags: map[byte]string{'e': "rsh", 'f': "filter", 'T': "temp-dir", 'B': "block-size", 'M': "remote-option"},
+	boolFlags: map[byte]string{'a': "archive", 'v': "verbose", 'z': "compress", 'r': "recursive",
+		'P': "partial-progress", 'h': "human-readable", 'n': "dry-run", 'u': "update", 'c': "checksum",
+		'l': "links", 'L': "copy-links", 'p': "perms", 't': "times", 'g': "group", 'o': "owner", 'D': "devices",
+		'q': "quiet", 'x': "one-file-system", 'H': "hard-links", 'A': "acls", 'X': "xattrs", 'S': "sparse",
+		'W': "whole-file", 'R': "relative", 'i': "itemize-changes", 'm': "prune-empty-dirs", 'k': "copy-dirlinks",
+		'K': "keep-dirlinks", 'b': "backup", 'd': "dirs", 'E': "executability", 'O': "omit-dir-times",
+		'J': "omit-link-times", 'F': "filter-rule", 'C': "cvs-exclude", 'y': "fuzzy", 'U': "atimes",
+		'N': "crtimes", 'I': "ignore-times", 's': "secluded-args", '0': "from0", '4': "ipv4", '6': "ipv6",
+		'8': "8-bit-output", 'V': "version"},
+}
+
+var zipFlags = flagGlueRule{
+	valueFlags: map[byte]string{'P': "password", 'd': "exdir", 'x': "exclude", 'i': "include", 'b': "temp-path",
+		'n': "suffixes", 't': "from-date", 'Z': "compression-method"},
+	boolFlags: map[byte]string{'r': "recurse-paths", 'q': "quiet", 'v': "verbose", 'j': "junk-paths",
+		'o': "overwrite", 'u': "update", 'l': "to-crlf", 'e': "encrypt", 'm': "move", 'y': "symlinks",
+		'T': "test", 'D': "no-dir-entries", '0': "store-only", '1': "fast", '9': "best", 'f': "freshen",
+		'F': "fix", 'g': "grow", 'A': "adjust-sfx", 'X': "no-extra", 'a': "ascii", 'c': "entry-comments",
+		'z': "archive-comment", 'k': "DOS-names", 'L': "license", 'S': "system-hidden", 'h': "help",
+		'p': "pipe"},
+}
+
+var sevenZipFlags = flagGlueRule{
+	valueFlags: map[byte]string{'p': "password", 'o': "output-dir", 't': "type", 'm': "method", 'x': "exclude",
+		'i': "include", 'v': "volume", 'w': "working-dir", 'a': "overwrite-mode", 's': "switch"},
+	boolFlags: map[byte]string{'y': "yes", 'r': "recurse"},
+}
+
+// Commands that glue values to their short flags
+var flagGlueRules = map[string]flagGlueRule{
+	"mysql":       mysqlFlags,
+	"mysqldump":   mysqlFlags,
+	"mysqladmin":  mysqlFlags,
+	"mysqlimport": mysqlFlags,
+	"mariadb":     mysqlFlags,
+	"psql":        psqlFlags,
+	"pg_dump":     pgDumpFlags,
+	"pg_restore":  pgDumpFlags,
+	"gcc":         compilerFlags,
+	"g++":         compilerFlags,
+	"cc":          compilerFlags,
+	"c++":         compilerFlags,
+	"clang":       compilerFlags,
+	"clang++":     compilerFlags,
+	"ssh":         sshFlags,
+	"scp":         scpFlags,
+	"sftp":        scpFlags,
+	"tar":         tarFlags,
+	"java":        javaFlags,
+	"grep":        grepFlags,
+	"egrep":       grepFlags,
+	"fgrep":       grepFlags,
+	"head":        headTailFlags,
+	"tail":        headTailFlags,
+	"make":        makeFlags,
+	"gmake":       makeFlags,
+	"curl":        curlFlags,
+	"sed":         sedFlags,
+	"gsed":        sedFlags,
+	"awk":         awkFlags,
+	"gawk":        awkFlags,
+	"cut":         cutFlags,
+	"sort":        sortFlags,
+	"python":      pythonFlags,
+	"python3":     pythonFlags,
+	"node":        nodeFlags,
+	"rsync":       rsyncFlags,
+	"zip":         zipFlags,
+	"unzip":       zipFlags,
+	"7z":          sevenZipFlags,
+}
+
+// expandGluedFlags rewrites the short options of a command that has a flag glue rule
+// as its canonical long options, dropping any values glued to them. For example
+// tar -xzfbackup.tgz becomes tar --extract --gzip --file. The arguments of commands
+// without a rule are returned as they are.
+func expandGluedFlags(command string, args []string) []string {
+	rule, ok := flagGlueRules[command]
+	if !ok {
+		return args
+	}
+	expanded := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...)
+		}
+		if len(arg) < 2 || arg[0] != '-' || arg[1] == '-' {
+			expanded = append(expanded, arg)
+			continue
+		}
+		expanded = append(expanded, rule.expand(arg[1:])...)
+	}
+	return expanded
+}
+
+// expand returns the canonical long options for a single dash option, without the dash
+func (r flagGlueRule) expand(option string) []string {
+	if name, ok := r.matchPrefix(option); ok {
+		return []string{"--" + name}
+	}
+	flags := make([]string, 0, len(option))
+	for i := 0; i < len(option); i++ {
+		c := option[i]
+		if name, ok := r.boolFlags[c]; ok {
+			flags = append(flags, "--"+name)
+			continue
+		}
+		if name, ok := r.valueFlags[c]; ok {
+			flags = append(flags, "--"+name)
+		} else {
+			// We don't know whether an unknown flag takes a value, so assume it does
+			// rather than risk recording the letters of its value as flags
+			flags = append(flags, "--"+string(c))
+		}
+		break
+	}
+	return flags
+}
+
+// matchPrefix returns the canonical name of the longest prefix flag matching option
+func (r flagGlueRule) matchPrefix(option string) (string, bool) {
+	prefixes := make([]string, 0, len(r.prefixFlags))
+	for prefix := range r.prefixFlags {
+		if strings.HasPrefix(option, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return "", false
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return r.prefixFlags[prefixes[0]], true
+}
@@ -0,0 +1,50 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestExpandGluedFlags(t *testing.T) {
+	assert.Equal(t, []string{"--extract", "--gzip", "--file", "backup.tgz"},
+		expandGluedFlags("tar", []string{"-xzf", "backup.tgz"}))
+	assert.Equal(t, []string{"--create", "--file"}, expandGluedFlags("tar", []string{"-cfbackup.tgz"}))
+	assert.Equal(t, []string{"--include-dir", "--define", "--optimize", "--std", "--output", "main", "main.c"},
+		expandGluedFlags("gcc", []string{"-I/usr/local/include", "-DKEY=val", "-O2", "-std=c11", "-o", "main", "main.c"}))
+	assert.Equal(t, []string{"--identity-file", "host"}, expandGluedFlags("ssh", []string{"-i~/.ssh/key", "host"}))
+	assert.Equal(t, []string{"--max-heap-size", "--vm-option", "--jar", "app.jar"},
+		expandGluedFlags("java", []string{"-Xmx4g", "-XX:+UseG1GC", "-jar", "app.jar"}))
+
+	// Long options and anything after -- are left alone
+	assert.Equal(t, []string{"--verbose", "--", "-xzf"}, expandGluedFlags("tar", []string{"--verbose", "--", "-xzf"}))
+
+	// An unknown flag might take a value, so the rest of its cluster is dropped
+	assert.Equal(t, []string{"--verbose", "--Q"}, expandGluedFlags("tar", []string{"-vQsecret"}))
+
+	// Commands without a rule are untouched
+	assert.Equal(t, []string{"-la"}, expandGluedFlags("ls", []string{"-la"}))
+}
+
+func TestRedactCommandGluedFlags(t *testing.T) {
+	r := RedactCommand(shell.Bash, []string{"ssh -i~/.ssh/work_key -p2222 deploy@host"})
+	assert.Equal(t, []string{"identity-file", "port"}, r.Options)
+
+	r = RedactCommand(shell.Bash, []string{"gcc -I/home/me/secret-project/include -DAPI_KEY=abc main.c"})
+	assert.Equal(t, []string{"include-dir", "define"}, r.Options)
+	assert.NotContains(t, r.Preview(), "secret-project")
+}
+
+func TestRedactCommandPostgresFlags(t *testing.T) {
+	r := RedactCommand(shell.Bash, []string{`psql -U admin -c "select * from users" app`})
+	assert.Equal(t, "psql", r.Command)
+	assert.Equal(t, []string{"username", "command"}, r.Options)
+	assert.NotContains(t, r.Preview(), "users")
+
+	r = RedactCommand(shell.Bash, []string{"psql -tAc 'select 1'"})
+	assert.Equal(t, []string{"tuples-only", "no-align", "command"}, r.Options)
+
+	r = RedactCommand(shell.Bash, []string{"pg_restore -c -d app backup.dump"})
+	assert.Equal(t, []string{"clean", "dbname"}, r.Options)
+}
@@ -0,0 +1,34 @@
+package history
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"log"
+)
+
+// commandHashKey keys the hashes of command lines. It is generated for each run of
+// the survey and never uploaded, so hashes can be compared within one respondent's
+// history but can't be reversed with a dictionary or matched across respondents.
+var commandHashKey = newCommandHashKey()
+
+func newCommandHashKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Println("Unable to generate a key for command hashes, commands won't be hashed", err)
+		return nil
+	}
+	return key
+}
+
+// hashCommand returns the hex HMAC-SHA256 of a command line under commandHashKey, or
+// an empty string if there is no key
+func hashCommand(line string) string {
+	if commandHashKey == nil {
+		return ""
+	}
+	h := hmac.New(sha256.New, commandHashKey)
+	h.Write([]byte(line))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -0,0 +1,99 @@
+package history
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// MergeHistories merges several histories into one, ordered by when each command ran.
+// Commands without a timestamp stay after the command before them in their own
+// history. A command that appears in more than one of the histories at the same time,
+// e.g. from a history file shared by two shells, is only kept once. Each command
+// records the history it came from, see CommandSource. The merged history has the
+// shell type of the first one.
+func MergeHistories(histories []*ShellHistory) *ShellHistory {
+	if len(histories) == 0 {
+		return nil
+	}
+	if len(histories) == 1 {
+		return histories[0]
+	}
+
+	fileNames := make([]string, 0, len(histories))
+	for _, h := range histories {
+		fileNames = append(fileNames, h.FileName)
+	}
+	merged := newShellHistory(strings.Join(fileNames, ", "), histories[0].ShellType)
+	merged.SuppressedRare = map[string]int{}
+
+	type cursor struct {
+		history *ShellHistory
+		next    int
+		last    time.Time
+	}
+	cursors := make([]*cursor, 0, len(histories))
+	for _, h := range histories {
+		cursors = append(cursors, &cursor{history: h})
+		merged.UnattributedLines += h.UnattributedLines
+		for reason, count := range h.SuppressedLines {
+			merged.SuppressedLines[reason] += count
+		}
+		for item, count := range h.SuppressedRare {
+			merged.SuppressedRare[item] += count
+		}
+	}
+
+	// The history each command was first seen in, by its hash and time
+	sources := map[string]*ShellHistory{}
+	for {
+		var earliest *cursor
+		var earliestTime time.Time
+		for _, c := range cursors {
+			if c.next >= len(c.history.RedactedLines) {
+				continue
+			}
+			t := c.last
+			if r := c.history.RedactedLines[c.next]; r != nil && !r.Timestamp.IsZero() {
+				t = r.Timestamp
+			}
+			if earliest == nil || t.Before(earliestTime) {
+				earliest, earliestTime = c, t
+			}
+		}
+		if earliest == nil {
+			break
+		}
+		r := earliest.history.RedactedLines[earliest.next]
+		earliest.next++
+		earliest.last = earliestTime
+		if r == nil {
+			continue
+		}
+
+		if !r.Timestamp.IsZero() && len(r.Hash) > 0 {
+			key := r.Hash + " " + r.Timestamp.String() + " " + strconv.Itoa(r.Position)
+			if source, ok := sources[key]; ok && source != earliest.history {
+				continue
+			}
+			sources[key] = earliest.history
+		}
+		if len(r.Source) == 0 {
+			r.Source = earliest.history.FileName
+			r.SourceShell = earliest.history.ShellType
+		}
+		merged.RedactedLines = append(merged.RedactedLines, r)
+	}
+	return merged
+}
+
+// CommandSource returns the history file a command was read from and the shell that
+// wrote it
+func (h *ShellHistory) CommandSource(r *RedactedCommand) (string, shell.Type) {
+	if len(r.Source) > 0 {
+		return r.Source, r.SourceShell
+	}
+	return h.FileName, h.ShellType
+}
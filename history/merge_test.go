@@ -0,0 +1,41 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestMergeHistories(t *testing.T) {
+	zsh := newShellHistory("/home/me/.zsh_history", shell.Zsh)
+	for _, line := range []string{": 1584112360:0;ls", ": 1584112370:0;git status", ": 1584112390:0;make"} {
+		zsh.redactEntry(shell.Zsh, []string{line})
+	}
+	zsh.SuppressedLines[secretJWT] = 1
+
+	bash := newShellHistory("/home/me/.bash_history", shell.Bash)
+	for _, lines := range [][]string{{"#1584112365", "pwd"}, {"cd src"}, {"#1584112370", "git status"}, {"#1584112380", "go test"}} {
+		bash.redactEntry(shell.Bash, lines)
+	}
+	bash.SuppressedLines[secretJWT] = 2
+
+	merged := MergeHistories([]*ShellHistory{zsh, bash})
+	assert.Equal(t, []string{"ls", "pwd", "cd", "git", "go", "make"}, commandNames(merged))
+	assert.Equal(t, "/home/me/.zsh_history, /home/me/.bash_history", merged.FileName)
+	assert.Equal(t, shell.Type(shell.Zsh), merged.ShellType)
+	assert.Equal(t, map[string]int{secretJWT: 3}, merged.SuppressedLines)
+
+	fileName, shellType := merged.CommandSource(merged.RedactedLines[2])
+	assert.Equal(t, "/home/me/.bash_history", fileName)
+	assert.Equal(t, shell.Type(shell.Bash), shellType)
+	fileName, shellType = merged.CommandSource(merged.RedactedLines[3])
+	assert.Equal(t, "/home/me/.zsh_history", fileName)
+	assert.Equal(t, shell.Type(shell.Zsh), shellType)
+}
+
+func TestMergeSingleHistory(t *testing.T) {
+	h := excludeTestHistory()
+	assert.Equal(t, h, MergeHistories([]*ShellHistory{h}))
+	assert.Nil(t, MergeHistories(nil))
+}
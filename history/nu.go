@@ -0,0 +1,198 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// Nu plaintext format (history.txt)
+// <command>
+//
+// Newlines inside a command are written as <\n>.
+//
+// Nu SQLite format (history.sqlite3) has a history table with a row per command,
+// including when it started, how long it ran, its exit status and working directory.
+
+const nuNewlineEscape = `<\n>`
+
+const nuHistoryQuery = `SELECT command_line, start_timestamp, duration_ms, exit_status, cwd
+FROM history ORDER BY id`
+
+// Column indexes for nuHistoryQuery
+const (
+	nuCommandColumn = iota
+	nuStartColumn
+	nuDurationColumn
+	nuExitStatusColumn
+	nuCwdColumn
+	nuNumColumns
+)
+
+// readNuHistory splits a nu history.txt file into entries, one per line.
+// Every line belongs to an entry, so the unattributed line count is always zero.
+func readNuHistory(reader *bufio.Reader, emit func(entry []string)) (int, error) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if command := strings.TrimSpace(line); len(command) > 0 {
+			emit([]string{command})
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return 0, nil
+}
+
+// parseNuLine returns the command of a line in nu's plaintext history
+func parseNuLine(line string) string {
+	return strings.Replace(line, nuNewlineEscape, "\n", -1)
+}
+
+// redactNuDatabase redacts every command in a nu history.sqlite3 file into history
+func redactNuDatabase(dbPath string, history *ShellHistory) error {
+	rows, err := querySQLite(dbPath, nuHistoryQuery)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if len(row) != nuNumColumns {
+			continue
+		}
+		var timestamp time.Time
+		if startMs, err := strconv.ParseInt(row[nuStartColumn], 10, 64); err == nil {
+			timestamp = time.Unix(0, startMs*int64(time.Millisecond))
+		}
+		var duration time.Duration
+		if durationMs, err := strconv.ParseInt(row[nuDurationColumn], 10, 64); err == nil {
+			duration = time.Duration(durationMs) * time.Millisecond
+		}
+		var exitStatus *int
+		if status, err := strconv.Atoi(row[nuExitStatusColumn]); err == nil {
+			exitStatus = &status
+		}
+		commands := history.redactEntry(shell.Nu, []string{row[nuCommandColumn]})
+		setCommandLineRun(commands, timestamp, duration, exitStatus, row[nuCwdColumn])
+	}
+	return nil
+}
+
+// splitNuCommand splits a nu command line into words. Unlike a POSIX shell nu has
+// no backslash escapes outside of double quotes, uses backticks and r#'...'# for
+// raw strings, and treats blocks, lists, records and subexpressions as single
+// values. Pipes and semicolons are returned as their own words.
+func splitNuCommand(line string) ([]string, error) {
+	words := make([]string, 0)
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+		}
+		word.Reset()
+		inWord = false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			endWord()
+		case c == '|' || c == ';':
+			endWord()
+			words = append(words, string(c))
+		case c == '"':
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, errors.New("Unterminated double-quoted string")
+			}
+			word.WriteString(line[i+1 : end])
+			inWord = true
+			i = end
+		case c == '\'' || c == '`':
+			end := strings.IndexByte(line[i+1:], c)
+			if end < 0 {
+				return nil, errors.New("Unterminated quoted string")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == 'r' && !inWord && strings.HasPrefix(line[i:], "r#"):
+			hashes := 0
+			for i+1+hashes < len(line) && line[i+1+hashes] == '#' {
+				hashes++
+			}
+			if i+1+hashes >= len(line) || line[i+1+hashes] != '\'' {
+				word.WriteByte(c)
+				inWord = true
+				continue
+			}
+			terminator := "'" + strings.Repeat("#", hashes)
+			start := i + 2 + hashes
+			end := strings.Index(line[start:], terminator)
+			if end < 0 {
+				return nil, errors.New("Unterminated raw string")
+			}
+			word.WriteString(line[start : start+end])
+			inWord = true
+			i = start + end + len(terminator) - 1
+		case c == '{' || c == '[' || c == '(':
+			end, err := matchNuBracket(line, i)
+			if err != nil {
+				return nil, err
+			}
+			word.WriteString(line[i : end+1])
+			inWord = true
+			i = end
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endWord()
+	return words, nil
+}
+
+// matchNuBracket returns the index of the bracket closing the one at start,
+// skipping over any quoted strings in between.
+func matchNuBracket(line string, start int) (int, error) {
+	depth := 0
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		case '"':
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case '\'', '`':
+			end := strings.IndexByte(line[i+1:], line[i])
+			if end < 0 {
+				return 0, errors.New("Unterminated quoted string")
+			}
+			i += end + 1
+		}
+	}
+	return 0, errors.New("Unterminated block")
+}
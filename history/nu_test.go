@@ -0,0 +1,92 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestSplitNuCommand(t *testing.T) {
+	words, err := splitNuCommand(`ls | where size > 10kb | sort-by name --reverse`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ls", "|", "where", "size", ">", "10kb", "|", "sort-by", "name", "--reverse"}, words)
+
+	words, err = splitNuCommand(`each { |it| $"($it.name) \"x\"" } ; echo 'a b' ` + "`c d`" + ` r#'e'f'#`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"each", `{ |it| $"($it.name) \"x\"" }`, ";", "echo", "a b", "c d", "e'f"}, words)
+
+	_, err = splitNuCommand(`echo "oops`)
+	assert.NotNil(t, err)
+	_, err = splitNuCommand(`each { |it| echo`)
+	assert.NotNil(t, err)
+}
+
+func TestRedactCommandNu(t *testing.T) {
+	rs := RedactCommands(shell.Nu, []string{`open data.json | get items --ignore-errors; ls`})
+	assert.Equal(t, 3, len(rs))
+	assert.Equal(t, "open", rs[0].Command)
+	assert.Equal(t, "|", rs[0].Operator)
+	assert.Equal(t, "get", rs[1].Command)
+	assert.Equal(t, 1, rs[1].PipelinePosition)
+	assert.Equal(t, []string{"ignore-errors"}, rs[1].Options)
+	assert.Equal(t, ";", rs[1].Operator)
+	assert.Equal(t, "ls", rs[2].Command)
+	assert.Equal(t, 0, rs[2].PipelinePosition)
+
+	r := RedactCommand(shell.Nu, []string{`open data.json`})
+	assert.Equal(t, "open", r.Command)
+
+	r = RedactCommand(shell.Nu, []string{`def greet [name] {<\n>  echo $name<\n>}`})
+	assert.Equal(t, "def", r.Command)
+}
+
+func TestRedactDirectory(t *testing.T) {
+	home := os.ExpandEnv("$HOME")
+	assert.Equal(t, "~/*/*", redactDirectory(home+"/src/secret-project"))
+	assert.Equal(t, "~", redactDirectory(home))
+	assert.Equal(t, "/*/*", redactDirectory("/opt/acme/"))
+	assert.Equal(t, "/", redactDirectory("/"))
+	assert.Equal(t, "", redactDirectory(""))
+}
+
+func TestRedactNuDatabase(t *testing.T) {
+	if !sqliteAvailable() {
+		t.Skip("sqlite3 is not installed")
+	}
+	dir, err := ioutil.TempDir("", "nushell")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "history.sqlite3")
+	err = exec.Command("sqlite3", dbPath, `
+CREATE TABLE history (id INTEGER PRIMARY KEY, command_line TEXT, start_timestamp INTEGER,
+  session_id INTEGER, hostname TEXT, cwd TEXT, duration_ms INTEGER, exit_status INTEGER, more_info TEXT);
+INSERT INTO history VALUES (1, 'git status', 1591025337000, 1, 'host', '/opt/work', 120, 0, NULL);
+INSERT INTO history VALUES (2, 'cargo build
+  --release', 1591025340500, 1, 'host', NULL, NULL, NULL, NULL);`).Run()
+	assert.Nil(t, err)
+
+	history := RedactHistoryFile(&dbPath, shell.Nu)
+	assert.NotNil(t, history)
+	assert.Equal(t, 2, len(history.RedactedLines))
+
+	r := history.RedactedLines[0]
+	assert.Equal(t, "git", r.Command)
+	assert.Equal(t, "status", r.Subcommand)
+	assert.Equal(t, time.Unix(1591025337, 0), r.Timestamp)
+	assert.Equal(t, 120*time.Millisecond, r.Duration)
+	assert.Equal(t, 0, *r.ExitStatus)
+	assert.Equal(t, "/*/*", r.Directory)
+
+	r = history.RedactedLines[1]
+	assert.Equal(t, "cargo", r.Command)
+	assert.Equal(t, []string{"release"}, r.Options)
+	assert.Nil(t, r.ExitStatus)
+	assert.Equal(t, "", r.Directory)
+}
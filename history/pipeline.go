@@ -0,0 +1,206 @@
+package history
+
+import (
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// simpleCommand is a single command within a command line, e.g. grep in
+// cat x | grep y && make
+type simpleCommand struct {
+	// text is the source of the command, words its words after quote removal
+	text  string
+	words []string
+
+	// pipelinePosition is the index of the command within its pipeline
+	pipelinePosition int
+
+	// operator follows the command: one of |, |&, &&, ||, ; or &, or empty
+	// if it is the last command on the line
+	operator string
+
+	// wrappers are shell keywords that wrap the command, like time
+	wrappers []string
+}
+
+// splitSimpleCommands parses a command line with the grammar of the given shell
+// and returns each of the simple commands in it, in the order they appear.
+// Commands nested inside compound commands (loops, conditionals, blocks and
+// function bodies) are included, commands inside substitutions are not.
+func splitSimpleCommands(shellType shell.Type, commandLine string) ([]simpleCommand, error) {
+	if shellType == shell.Nu {
+		return splitNuSimpleCommands(commandLine)
+	}
+
+	// zsh, fish and PowerShell are close enough to bash that the bash grammar
+	// splits most of their command lines correctly, and anything it can't parse
+	// falls back to being treated as one command.
+	parser := syntax.NewParser(syntax.Variant(syntax.LangBash))
+	file, err := parser.Parse(strings.NewReader(commandLine), "")
+	if err != nil {
+		return nil, err
+	}
+	splitter := &commandSplitter{shellType: shellType, commandLine: commandLine}
+	splitter.stmts(file.Stmts, 0, "")
+	return splitter.commands, nil
+}
+
+// commandSplitter walks a parsed command line collecting simple commands
+type commandSplitter struct {
+	shellType   shell.Type
+	commandLine string
+	commands    []simpleCommand
+}
+
+// stmts splits a list of statements. The last statement is followed by
+// lastOperator unless it runs in the background.
+func (s *commandSplitter) stmts(stmts []*syntax.Stmt, pipelinePosition int, lastOperator string) {
+	for i, stmt := range stmts {
+		operator := lastOperator
+		if stmt.Background {
+			operator = "&"
+		} else if i < len(stmts)-1 {
+			operator = ";"
+		}
+		s.stmt(stmt, pipelinePosition, operator)
+	}
+}
+
+func (s *commandSplitter) stmt(stmt *syntax.Stmt, pipelinePosition int, operator string) {
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.CallExpr:
+		s.call(cmd, pipelinePosition, operator)
+	case *syntax.BinaryCmd:
+		switch cmd.Op {
+		case syntax.Pipe, syntax.PipeAll:
+			stages := pipelineStages(stmt)
+			for i, stage := range stages {
+				stageOperator := operator
+				if i < len(stages)-1 {
+					stageOperator = cmd.Op.String()
+				}
+				s.stmt(stage, i, stageOperator)
+			}
+		default:
+			s.stmt(cmd.X, 0, cmd.Op.String())
+			s.stmt(cmd.Y, 0, operator)
+		}
+	case *syntax.Block:
+		s.stmts(cmd.Stmts, pipelinePosition, operator)
+	case *syntax.Subshell:
+		s.stmts(cmd.Stmts, pipelinePosition, operator)
+	case *syntax.IfClause:
+		for clause := cmd; clause != nil; clause = clause.Else {
+			s.stmts(clause.Cond, pipelinePosition, ";")
+			s.stmts(clause.Then, pipelinePosition, operator)
+		}
+	case *syntax.WhileClause:
+		s.stmts(cmd.Cond, pipelinePosition, ";")
+		s.stmts(cmd.Do, pipelinePosition, operator)
+	case *syntax.ForClause:
+		s.stmts(cmd.Do, pipelinePosition, operator)
+	case *syntax.CaseClause:
+		for _, item := range cmd.Items {
+			s.stmts(item.Stmts, pipelinePosition, operator)
+		}
+	case *syntax.FuncDecl:
+		s.stmt(cmd.Body, pipelinePosition, operator)
+	case *syntax.TimeClause:
+		if cmd.Stmt != nil {
+			first := len(s.commands)
+			s.stmt(cmd.Stmt, pipelinePosition, operator)
+			for i := first; i < len(s.commands); i++ {
+				s.commands[i].wrappers = append([]string{"time"}, s.commands[i].wrappers...)
+			}
+		}
+	case *syntax.CoprocClause:
+		s.stmt(cmd.Stmt, pipelinePosition, operator)
+	case *syntax.DeclClause:
+		// export, local, declare etc. Keep just the keyword, the rest is
+		// variable names and values.
+		s.commands = append(s.commands, simpleCommand{
+			text:             cmd.Variant.Value,
+			words:            []string{cmd.Variant.Value},
+			pipelinePosition: pipelinePosition,
+			operator:         operator})
+	}
+}
+
+func (s *commandSplitter) call(cmd *syntax.CallExpr, pipelinePosition int, operator string) {
+	if len(cmd.Args) == 0 {
+		// Just variable assignments
+		return
+	}
+	text := s.commandLine[cmd.Pos().Offset():cmd.End().Offset()]
+	words, err := shellquote.Split(text)
+	if err != nil || len(words) == 0 {
+		return
+	}
+
+	// fish chains commands with "; and" and "; or" as well as && and ||
+	if s.shellType == shell.Fish && (words[0] == "and" || words[0] == "or") && len(s.commands) > 0 {
+		if words[0] == "and" {
+			s.commands[len(s.commands)-1].operator = "&&"
+		} else {
+			s.commands[len(s.commands)-1].operator = "||"
+		}
+		text = strings.TrimSpace(text[len(words[0]):])
+		words = words[1:]
+		if len(words) == 0 {
+			return
+		}
+	}
+
+	s.commands = append(s.commands, simpleCommand{
+		text:             text,
+		words:            words,
+		pipelinePosition: pipelinePosition,
+		operator:         operator})
+}
+
+// pipelineStages flattens a pipeline into its stages, in order
+func pipelineStages(stmt *syntax.Stmt) []*syntax.Stmt {
+	cmd, ok := stmt.Cmd.(*syntax.BinaryCmd)
+	if !ok || (cmd.Op != syntax.Pipe && cmd.Op != syntax.PipeAll) {
+		return []*syntax.Stmt{stmt}
+	}
+	return append(pipelineStages(cmd.X), pipelineStages(cmd.Y)...)
+}
+
+// splitNuSimpleCommands splits a nu command line at its pipes and semicolons
+func splitNuSimpleCommands(commandLine string) ([]simpleCommand, error) {
+	words, err := splitNuCommand(commandLine)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]simpleCommand, 0)
+	pipelinePosition := 0
+	start := 0
+	for i := 0; i <= len(words); i++ {
+		if i < len(words) && words[i] != "|" && words[i] != ";" {
+			continue
+		}
+		operator := ""
+		if i < len(words) {
+			operator = words[i]
+		}
+		if i > start {
+			commands = append(commands, simpleCommand{
+				text:             strings.Join(words[start:i], " "),
+				words:            words[start:i],
+				pipelinePosition: pipelinePosition,
+				operator:         operator})
+		}
+		if operator == "|" {
+			pipelinePosition++
+		} else {
+			pipelinePosition = 0
+		}
+		start = i + 1
+	}
+	return commands, nil
+}
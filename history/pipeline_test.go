@@ -0,0 +1,69 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+type expectedCommand struct {
+	command          string
+	pipelinePosition int
+	operator         string
+}
+
+func assertCommands(t *testing.T, expected []expectedCommand, rs []*RedactedCommand) {
+	assert.Equal(t, len(expected), len(rs))
+	for i := range expected {
+		if i >= len(rs) {
+			return
+		}
+		assert.Equal(t, expected[i].command, rs[i].Command)
+		assert.Equal(t, expected[i].pipelinePosition, rs[i].PipelinePosition)
+		assert.Equal(t, expected[i].operator, rs[i].Operator)
+		assert.Equal(t, i, rs[i].Position)
+	}
+}
+
+func TestRedactCommandsPipeline(t *testing.T) {
+	rs := RedactCommands(shell.Bash, []string{"cat x | grep -v y | wc -l && make || echo failed; sleep 10 &"})
+	assertCommands(t, []expectedCommand{
+		{"cat", 0, "|"},
+		{"grep", 1, "|"},
+		{"wc", 2, "&&"},
+		{"make", 0, "||"},
+		{"echo", 0, ";"},
+		{"sleep", 0, "&"}}, rs)
+	assert.Equal(t, []string{"invert-match"}, rs[1].Options)
+	assert.Equal(t, len("grep -v y"), rs[1].Length)
+}
+
+func TestRedactCommandsCompound(t *testing.T) {
+	rs := RedactCommands(shell.Bash, []string{"for f in *.go; do gofmt -l $f; done; if [ -d x ]; then rm -r x; fi"})
+	assertCommands(t, []expectedCommand{
+		{"gofmt", 0, ";"},
+		{"[", 0, ";"},
+		{"rm", 0, ""}}, rs)
+}
+
+func TestRedactCommandsSkipsSubstitutionsAndAssignments(t *testing.T) {
+	rs := RedactCommands(shell.Bash, []string{"FOO=bar; export PATH=$PATH:/secret; echo $(cat /etc/passwd)"})
+	assertCommands(t, []expectedCommand{
+		{"export", 0, ";"},
+		{"echo", 0, ""}}, rs)
+}
+
+func TestRedactCommandsFishAndOr(t *testing.T) {
+	rs := RedactCommands(shell.Fish, []string{"- cmd: make; and make install; or echo failed"})
+	assertCommands(t, []expectedCommand{
+		{"make", 0, "&&"},
+		{"make", 0, "||"},
+		{"echo", 0, ""}}, rs)
+}
+
+func TestRedactCommandsUnparseableFallsBack(t *testing.T) {
+	// A fish command substitution isn't valid bash, so it's treated as one command
+	rs := RedactCommands(shell.Fish, []string{"- cmd: echo (date) | cat"})
+	assertCommands(t, []expectedCommand{{"echo", 0, ""}}, rs)
+}
@@ -0,0 +1,46 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// PowerShell format (PSReadLine's ConsoleHost_history.txt)
+// <command>
+//
+// There are no timestamps. Each line of a multi-line command except the last
+// ends with a backtick, after the backtick the user typed if they continued the
+// line with one.
+
+// readPowerShellHistory splits a PSReadLine history file into entries, joining the
+// lines of multi-line commands with spaces so they're redacted as a single command.
+// Every line belongs to an entry, so the unattributed line count is always zero.
+func readPowerShellHistory(reader *bufio.Reader, emit func(entry []string)) (int, error) {
+	var entry strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if entry.Len() > 0 {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if strings.HasSuffix(line, "`") && err != io.EOF {
+			// Drop the continuation backtick, and the user's if they typed one
+			entry.WriteString(strings.TrimRight(line, "` \t"))
+			entry.WriteByte(' ')
+			continue
+		}
+		entry.WriteString(line)
+		if command := strings.TrimSpace(entry.String()); len(command) > 0 {
+			emit([]string{command})
+		}
+		entry.Reset()
+		if err == io.EOF {
+			break
+		}
+	}
+	return 0, nil
+}
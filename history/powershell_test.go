@@ -0,0 +1,63 @@
+package history
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestReadPowerShellHistory(t *testing.T) {
+	file := "Get-ChildItem -Recurse\r\n" +
+		"if ($x) {`\r\n" +
+		"  Write-Host hi`\r\n" +
+		"}\r\n" +
+		"git commit ```\r\n" +
+		"  -m wip\r\n"
+	entries, _, err := readAllEntries(readPowerShellHistory, bufio.NewReader(strings.NewReader(file)))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Get-ChildItem -Recurse"},
+		{"if ($x) { Write-Host hi }"},
+		{"git commit -m wip"}}, entries)
+}
+
+func TestRedactCommandPowerShell(t *testing.T) {
+	r := RedactCommand(shell.PowerShell, []string{"Get-ChildItem -Recurse -Filter *.go"})
+	assert.Equal(t, "Get-ChildItem", r.Command)
+	assert.Equal(t, []string{"Recurse", "Filter"}, r.Options)
+
+	// PowerShell ignores case, so cmdlets are counted under their usual spelling
+	r = RedactCommand(shell.PowerShell, []string{"get-childitem -Recurse"})
+	assert.Equal(t, "Get-ChildItem", r.Command)
+	r = RedactCommand(shell.PowerShell, []string{"SELECT-STRING -Pattern foo"})
+	assert.Equal(t, "Select-String", r.Command)
+}
+
+func TestRedactPowerShellContinuedCommand(t *testing.T) {
+	file, err := ioutil.TempFile("", "ConsoleHost_history.txt")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("Get-ChildItem -Path C:\\src ``\r\n  -Filter *.go `\r\n  -Recurse\r\ngit commit ```\r\n  -m wip\r\n")
+	file.Close()
+
+	path := file.Name()
+	history := RedactHistoryFile(&path, shell.PowerShell)
+	assert.NotNil(t, history)
+	assert.Equal(t, 2, len(history.RedactedLines))
+	assert.Equal(t, "Get-ChildItem", history.RedactedLines[0].Command)
+	assert.Equal(t, []string{"Path", "Filter", "Recurse"}, history.RedactedLines[0].Options)
+	assert.Equal(t, "git", history.RedactedLines[1].Command)
+	assert.Equal(t, "commit", history.RedactedLines[1].Subcommand)
+	assert.Equal(t, []string{"m"}, history.RedactedLines[1].Options)
+}
+
+func TestGetShellTypePowerShell(t *testing.T) {
+	assert.Equal(t, shell.Type(shell.PowerShell), shell.GetShellType("/usr/bin/pwsh"))
+	assert.Equal(t, shell.Type(shell.PowerShell),
+		shell.GetShellType("/home/me/.local/share/powershell/PSReadLine/ConsoleHost_history.txt"))
+}
@@ -0,0 +1,122 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// The shell hooks installed by dsurvey record pass each command line the user runs to
+// RecordCommand, which redacts it and appends it to the record log. Only the redacted
+// commands are written, one JSON object per line, so the original text never touches
+// the disk.
+
+// CommandRun is a command line run in an interactive shell, as the record hooks saw it
+type CommandRun struct {
+	ShellType   shell.Type
+	CommandLine string
+	Start       time.Time
+	Duration    time.Duration
+	ExitStatus  int
+
+	// Directory is the working directory, redacted before it is written
+	Directory string
+}
+
+// recordedEntry is a line of the record log
+type recordedEntry struct {
+	ShellType shell.Type
+	Redaction
+}
+
+// maxRecordedEntrySize is the longest line of the record log we read
+const maxRecordedEntrySize = 1024 * 1024
+
+// RecordLogPath returns where the record hooks write commands
+func RecordLogPath() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if len(dataHome) == 0 {
+		dataHome = os.ExpandEnv("$HOME/.local/share")
+	}
+	return filepath.Join(dataHome, "warp-survey", "recorded_commands.jsonl")
+}
+
+// GetRecordedHistoryFile returns the path of the record log, if anything has been
+// recorded
+func GetRecordedHistoryFile() (string, error) {
+	logPath := RecordLogPath()
+	if _, err := os.Stat(logPath); err != nil {
+		return "", err
+	}
+	return logPath, nil
+}
+
+// RecordCommand redacts a command line and appends it to the record log at logPath.
+// Hashes are left out, as the key they're made with only lasts for one run of the
+// survey.
+func RecordCommand(logPath string, run CommandRun) error {
+	redaction := redactParsedEntry(run.ShellType, run.Start, run.Duration, run.CommandLine)
+	exitStatus := run.ExitStatus
+	setCommandLineRun(redaction.Commands, run.Start, run.Duration, &exitStatus, run.Directory)
+	for _, r := range redaction.Commands {
+		r.Hash = ""
+	}
+	line, err := json.Marshal(recordedEntry{ShellType: run.ShellType, Redaction: redaction})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logPath), 0700); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := logFile.Write(append(line, '\n')); err != nil {
+		logFile.Close()
+		return err
+	}
+	return logFile.Close()
+}
+
+// RedactRecordedHistory reads the commands in a record log. They were redacted when
+// they were recorded, so the preview can't compare them with the original commands.
+// Commands recorded in a shell other than shellType are attributed to it, see
+// CommandSource. Returns nil if the log can't be read.
+func RedactRecordedHistory(logPath string, shellType shell.Type) *ShellHistory {
+	log.Println("Reading recorded commands", logPath)
+	logFile, err := os.Open(logPath)
+	if err != nil {
+		log.Println("Error reading recorded commands", err)
+		return nil
+	}
+	defer logFile.Close()
+
+	history := newShellHistory(logPath, shellType)
+	scanner := bufio.NewScanner(logFile)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordedEntrySize)
+	for scanner.Scan() {
+		var entry recordedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			history.UnattributedLines++
+			continue
+		}
+		for _, r := range history.Add(entry.Redaction) {
+			if r != nil && entry.ShellType != shellType {
+				r.Source = logPath
+				r.SourceShell = entry.ShellType
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("Error reading recorded commands", err)
+		return nil
+	}
+	return history
+}
@@ -0,0 +1,73 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestRecordCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "warp-survey", "recorded_commands.jsonl")
+
+	start := time.Unix(1584112360, 0)
+	assert.Nil(t, RecordCommand(logPath, CommandRun{ShellType: shell.Bash,
+		CommandLine: "git commit -m 'fix the widget' && make", Start: start, Duration: 3 * time.Second,
+		ExitStatus: 2, Directory: "/srv/widgets"}))
+	assert.Nil(t, RecordCommand(logPath, CommandRun{ShellType: shell.Zsh,
+		CommandLine: "export GITHUB_TOKEN=" + fakeGitHubToken, Start: start}))
+
+	contents, err := ioutil.ReadFile(logPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(contents), "\n"))
+	assert.NotContains(t, string(contents), "widget")
+	assert.NotContains(t, string(contents), fakeGitHubToken)
+
+	history := RedactRecordedHistory(logPath, shell.Bash)
+	assert.NotNil(t, history)
+	assert.Equal(t, []string{"git", "make"}, commandNames(history))
+	assert.Equal(t, map[string]int{"GitHub token": 1}, history.SuppressedLines)
+	for _, r := range history.RedactedLines {
+		assert.True(t, start.Equal(r.Timestamp))
+		assert.Equal(t, 3*time.Second, r.Duration)
+		assert.Equal(t, 2, *r.ExitStatus)
+		assert.Equal(t, "/*/*", r.Directory)
+		assert.Equal(t, "", r.Hash)
+		assert.Empty(t, r.Audit(true, true))
+	}
+	assert.Equal(t, []string{"m"}, history.RedactedLines[0].Options)
+}
+
+func TestRedactRecordedHistoryAttributesShells(t *testing.T) {
+	file, err := ioutil.TempFile("", "recorded_commands")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.Close()
+
+	assert.Nil(t, RecordCommand(file.Name(), CommandRun{ShellType: shell.Zsh, CommandLine: "ls"}))
+	assert.Nil(t, RecordCommand(file.Name(), CommandRun{ShellType: shell.Fish, CommandLine: "pwd"}))
+	appendFile, err := os.OpenFile(file.Name(), os.O_APPEND|os.O_WRONLY, 0600)
+	assert.Nil(t, err)
+	appendFile.WriteString("{\"ShellType\":\"Fish\",\"Comm\n")
+	appendFile.Close()
+
+	history := RedactRecordedHistory(file.Name(), shell.Zsh)
+	assert.NotNil(t, history)
+	assert.Equal(t, 1, history.UnattributedLines)
+	assert.Equal(t, []string{"ls", "pwd"}, commandNames(history))
+	fileName, shellType := history.CommandSource(history.RedactedLines[0])
+	assert.Equal(t, file.Name(), fileName)
+	assert.Equal(t, shell.Type(shell.Zsh), shellType)
+	_, shellType = history.CommandSource(history.RedactedLines[1])
+	assert.Equal(t, shell.Type(shell.Fish), shellType)
+
+	assert.Nil(t, RedactRecordedHistory(file.Name()+".missing", shell.Zsh))
+}
@@ -2,15 +2,11 @@ package history
 
 import (
 	"bufio"
-	"bytes"
-	"crypto/sha1"
 	"errors"
-	"fmt"
-	"io"
+	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,21 +16,26 @@ import (
 	"github.com/warpdotdev/warp-cli-survey/shell"
 )
 
-// Whitelisted commands that we know have subcommands
-var hasSubcommand = map[string]bool{
-	"git":    true,
-	"yarn":   true,
-	"npm":    true,
-	"aws":    true,
-	"gcloud": true,
-	"go":     true,
-}
-
 // ShellHistory models a shell history file
 type ShellHistory struct {
 	FileName      string
 	ShellType     shell.Type
 	RedactedLines []*RedactedCommand
+
+	// UnattributedLines is the number of lines in the file that could not be
+	// attributed to a command, or to the timestamp of a command
+	UnattributedLines int
+
+	// SuppressedLines counts the lines that were dropped because they looked like
+	// they held a secret, by the kind of secret
+	SuppressedLines map[string]int
+
+	// SuppressedRare counts the rarely used commands and flags generalized by
+	// SuppressRare, by command or command and flag
+	SuppressedRare map[string]int
+
+	// exclusions are the commands the user left out, most recent last, see ExcludeRange
+	exclusions []exclusion
 }
 
 // RedactedCommand models a single command in a shell history file
@@ -44,10 +45,55 @@ type RedactedCommand struct {
 	Options    []string
 	NumTokens  int
 	Length     int
-	Sha1       string
+
+	// Hash is a keyed hash of the command's text, see hashCommand
+	Hash string
+
+	// SubcommandPath is every level of subcommand, e.g. compute instances list for
+	// gcloud compute instances list. Subcommand is its first element.
+	SubcommandPath []string
 
 	// Not available in all history formats
 	Timestamp time.Time
+	Duration  time.Duration
+
+	// ExitStatus is nil unless the history format records it
+	ExitStatus *int
+
+	// Wrappers are commands like sudo or xargs that ran Command, outermost first, and
+	// EnvVars the names of variables set for it, e.g. FOO for FOO=bar make.
+	// Variable values are never kept.
+	Wrappers []string
+	EnvVars  []string
+
+	// Position is the index of the command among the commands on its line,
+	// PipelinePosition its index within its pipeline, and Operator the operator
+	// following it (|, |&, &&, ||, ; or &) or empty if it is last on the line.
+	Position         int
+	PipelinePosition int
+	Operator         string
+
+	// Directory is the working directory the command ran in, if the history format
+	// records it. Path components are replaced by * so only the depth and whether it
+	// was under the home directory are kept, e.g. ~/*/*
+	Directory string
+
+	// MaskedSecrets is the kind of secret behind each option whose value was masked,
+	// e.g. password flag for mysql -pHunter2
+	MaskedSecrets []string
+
+	// Source is the history file the command was read from and SourceShell the shell
+	// that wrote it, if it came from one of several merged histories
+	Source      string
+	SourceShell shell.Type
+
+	// Session identifies the terminal session that ran the command, for histories
+	// kept in a file per session, see RedactHistoryDir
+	Session string
+
+	// source is the original text of the command, kept unexported so it can only be
+	// shown to the user by Audit and never copied into anything that is uploaded
+	source string
 }
 
 // GetRedactedShellHistory returns a model of the shell history for the given shell type.
@@ -58,7 +104,7 @@ func GetRedactedShellHistory(targetShellType shell.Type, historyFilePath *string
 	if historyFilePath != nil {
 		history = RedactHistoryFile(historyFilePath, targetShellType)
 	} else {
-		historyFilePath, err := getHistoryFile(targetShellType)
+		historyFilePath, err := FindHistoryFile(targetShellType)
 		if err != nil {
 			log.Println("Unable to locate history file for", targetShellType)
 			return
@@ -68,47 +114,35 @@ func GetRedactedShellHistory(targetShellType shell.Type, historyFilePath *string
 	return
 }
 
-func getHistoryFile(targetShellType shell.Type) (string, error) {
-	home := os.ExpandEnv("$HOME")
-
-	for _, dir := range []string{home, home + "/.local/share/fish/"} {
-		cmd := exec.Command("ls", "-a", dir)
-		var out bytes.Buffer
-		cmd.Stdout = &out
-		err := cmd.Run()
-		if err != nil {
-			log.Println("Error searching for history file in dir", dir, err)
-			continue
-		}
-		m := strings.Split(out.String(), "\n")
-		for _, fileName := range m {
-			if strings.Contains(fileName, "history") && targetShellType == shell.GetShellType(fileName) {
-				return dir + "/" + fileName, nil
-			}
+// FindHistoryFile returns the most likely history file for the shell type
+func FindHistoryFile(targetShellType shell.Type) (string, error) {
+	for _, candidate := range DiscoverHistoryFiles() {
+		if candidate.ShellType == targetShellType && !candidate.Sessions {
+			return candidate.Path, nil
 		}
 	}
-
 	return "", errors.New("History file not found")
 }
 
-// Fish format
-// - cmd: <cmd>
-//   when: <timestamp>
-
-// ZshHistoryLineRegEx parses a single line of a zsh history file.
-// Zsh format
-// : <timestamp>:0;<command>
-var ZshHistoryLineRegEx = regexp.MustCompile(`^: (\d+):\d+;(.*)$`)
+// historyReader splits a history file into entries that can be passed to RedactCommand,
+// passing each one to emit as soon as it has been read, and counts the lines it could
+// not attribute to any command.
+type historyReader func(reader *bufio.Reader, emit func(entry []string)) (unattributed int, err error)
 
-// Bash format if HISTTIMEFORMAT not set
-// <command>
+// Readers for each of the history file formats we understand
+var historyReaders = map[shell.Type]historyReader{
+	shell.Bash: readBashHistory,
+	shell.Fish: readFishHistory,
+	shell.Zsh:  readZshHistory,
 
-// Bash format if HISTTIMEFORMAT set
-// #<timestamp>
-// <command>
+	shell.PowerShell: readPowerShellHistory,
+	shell.Nu:         readNuHistory,
+}
 
 // RedactHistoryFile redacts a single shell history file of the given shell type.
-// Returns nil if the history file and target shell type don't match
+// The shell that wrote the file is detected from its contents, and the file is read
+// as the target shell's unless they clearly show another shell wrote it, in which
+// case nil is returned.
 func RedactHistoryFile(historyFilePath *string, targetShellType shell.Type) *ShellHistory {
 	log.Println("Reading history file", *historyFilePath)
 	historyFile, openErr := os.Open(*historyFilePath)
@@ -118,89 +152,160 @@ func RedactHistoryFile(historyFilePath *string, targetShellType shell.Type) *She
 	}
 	defer historyFile.Close()
 
-	shellType := shell.GetShellType(historyFile.Name())
-	if shellType == targetShellType {
-		history := &ShellHistory{
-			FileName:      historyFile.Name(),
-			ShellType:     shellType,
-			RedactedLines: make([]*RedactedCommand, 0)}
-
-		reader := bufio.NewReader(historyFile)
+	shellType, confidence := shell.DetectShellType(historyFile.Name())
+	log.Println("History file looks like", shellType, "history, confidence", confidence)
+	if shellType == shell.Unknown || confidence < 0.5 {
+		// Nothing in the file says which shell wrote it, so trust the caller
+		shellType = targetShellType
+	}
+	if shellType == targetShellType && historyReaders[shellType] != nil {
+		return redactOpenHistoryFile(historyFile, shellType)
+	}
+	return nil
+}
 
-		linesAtATime := 1
-		if shellType == shell.Fish {
-			linesAtATime = 2
-		}
+// redactOpenHistoryFile redacts a history file already known to be of the given shell
+// type, or returns nil if it can't be read
+func redactOpenHistoryFile(historyFile *os.File, shellType shell.Type) *ShellHistory {
+	history := newShellHistory(historyFile.Name(), shellType)
 
-		i := 0
-		for {
-			lines := make([]string, 0)
-			line, err := reader.ReadString('\n')
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				log.Println("Error reading history file", err)
-				return nil
-			}
-			lines = append(lines, strings.TrimSpace(line))
-			if shellType == shell.Bash {
-				if line[0] == '#' {
-					// Looks like HISTTIMEFORMAT has been set, so parse two lines at a time.
-					_, err := strconv.Atoi(lines[0][1:])
-					if err == nil {
-						linesAtATime = 2
-					}
-				} else {
-					linesAtATime = 1
-				}
-			}
-			if linesAtATime == 2 && i%2 == 0 {
-				line, readErr := reader.ReadString('\n')
-				if readErr != nil {
-					log.Println("Error reading history file, skipping.")
-				}
-				lines = append(lines, strings.TrimSpace(line))
-			}
-			r := RedactCommand(shellType, lines)
-			if r != nil {
-				history.RedactedLines = append(history.RedactedLines, r)
-			}
-			i += linesAtATime
+	if shellType == shell.Nu && isSQLiteFile(historyFile) {
+		if err := redactNuDatabase(historyFile.Name(), history); err != nil {
+			log.Println("Error reading history database", err)
+			return nil
 		}
 		return history
 	}
-	return nil
+
+	stream := streamHistory(ioutil.NopCloser(historyFile), historyFile.Name(), shellType)
+	for redaction := range stream.Redactions {
+		history.Add(redaction)
+	}
+	unattributed, err := stream.Wait()
+	if err != nil {
+		log.Println("Error reading history file", err)
+		return nil
+	}
+	if unattributed > 0 {
+		log.Println("Unable to attribute", unattributed, "lines of the history file to a command")
+	}
+	history.UnattributedLines = unattributed
+	return history
+}
+
+func newShellHistory(fileName string, shellType shell.Type) *ShellHistory {
+	return &ShellHistory{
+		FileName:        fileName,
+		ShellType:       shellType,
+		RedactedLines:   make([]*RedactedCommand, 0),
+		SuppressedLines: map[string]int{}}
+}
+
+// redactEntry redacts a single entry of a history file and adds its commands to the
+// history, returning them. Entries that look like they hold a secret are dropped and
+// counted in SuppressedLines instead.
+func (h *ShellHistory) redactEntry(shellType shell.Type, lines []string) []*RedactedCommand {
+	return h.Add(redactHistoryEntry(shellType, lines))
+}
+
+// setCommandLineRun records when a command line ran, how long it took, its exit status
+// and the directory it ran in, redacted, on each of its commands. The duration and exit
+// status are for the whole command line.
+func setCommandLineRun(commands []*RedactedCommand, timestamp time.Time, duration time.Duration,
+	exitStatus *int, directory string) {
+	redactedDirectory := redactDirectory(directory)
+	for _, r := range commands {
+		r.Timestamp = timestamp
+		r.Duration = duration
+		r.ExitStatus = exitStatus
+		r.Directory = redactedDirectory
+	}
 }
 
 // RedactCommand redacts a single line of a history file given a shell type
-// and returns the redacted command or nil if there was an error parsing
+// and returns the first redacted command on it, or nil if there was an error parsing.
+// Use RedactCommands to get every command on the line.
 func RedactCommand(shellType shell.Type, lines []string) *RedactedCommand {
+	redactedCommands := RedactCommands(shellType, lines)
+	if len(redactedCommands) == 0 {
+		return nil
+	}
+	return redactedCommands[0]
+}
+
+// RedactCommands redacts a single line of a history file given a shell type and
+// returns a redacted command for each simple command on it, e.g. cat, grep and make
+// for cat x | grep y && make.
+// Returns an empty slice if there was an error parsing.
+func RedactCommands(shellType shell.Type, lines []string) []*RedactedCommand {
 	// log.Println("redacting lines", shellType, lines)
 
-	commandTime, commandLine := ParseLines(shellType, lines)
-	redacted := new(RedactedCommand)
-	redacted.Length = len(commandLine)
-	redacted.Timestamp = commandTime
+	commandTime, duration, commandLine := ParseLines(shellType, lines)
+	return redactCommandLine(shellType, commandTime, duration, commandLine)
+}
 
-	splitLine, err := shellquote.Split(commandLine)
-	if err != nil || len(splitLine) == 0 {
-		// log.Println("Unable to parse command line, skipping", commandLine)
+// redactCommandLine redacts each simple command on a command line that has already
+// been parsed out of a history entry
+func redactCommandLine(shellType shell.Type, commandTime time.Time, duration time.Duration,
+	commandLine string) []*RedactedCommand {
+	redactedCommands := make([]*RedactedCommand, 0)
+
+	simpleCommands, err := splitSimpleCommands(shellType, commandLine)
+	if err != nil {
+		// Not something the shell grammar understands, treat it as one command
+		words, err := splitCommandLine(shellType, commandLine)
+		if err != nil {
+			// log.Println("Unable to parse command line, skipping", commandLine)
+			return redactedCommands
+		}
+		simpleCommands = []simpleCommand{{text: commandLine, words: words}}
+	}
+
+	for _, simple := range simpleCommands {
+		effective, wrappers, envVars := unwrapCommand(simple.words)
+		effective, maskedSecrets := maskSecretOptions(shellType, effective)
+		redacted := redactWords(effective)
+		if redacted == nil {
+			continue
+		}
+		redacted.MaskedSecrets = maskedSecrets
+		redacted.NumTokens = len(simple.words)
+		redacted.Wrappers = append(simple.wrappers, wrappers...)
+		redacted.EnvVars = envVars
+		redacted.Length = len(simple.text)
+		redacted.Hash = hashCommand(simple.text)
+		redacted.source = simple.text
+		redacted.Timestamp = commandTime
+		redacted.Duration = duration
+		redacted.Position = len(redactedCommands)
+		redacted.PipelinePosition = simple.pipelinePosition
+		redacted.Operator = simple.operator
+		redactedCommands = append(redactedCommands, redacted)
+	}
+	return redactedCommands
+}
+
+// redactWords redacts the words of a single simple command, keeping the command,
+// subcommand and option names, or returns nil if there was an error parsing
+func redactWords(splitLine []string) *RedactedCommand {
+	if len(splitLine) == 0 {
 		return nil
 	}
 
-	argsIdx := 1
 	var subcommand string
-	command := splitLine[0]
-	if hasSubcommand[command] && len(splitLine) > 1 {
-		subcommand = splitLine[1]
-		argsIdx++
+	command := normalizeCommand(splitLine[0])
+	subcommandPath, args := findSubcommandPath(append([]string{command}, splitLine[1:]...))
+	args = expandGluedFlags(command, args)
+	if len(subcommandPath) > 0 {
+		subcommand = subcommandPath[0]
 	}
 	parser := flags.NewNamedParser(command, flags.None)
 
-	redacted.Sha1 = getSha1Hex(commandLine)
+	redacted := new(RedactedCommand)
 	redacted.NumTokens = len(splitLine)
 	redacted.Command = command
 	redacted.Subcommand = subcommand
+	redacted.SubcommandPath = subcommandPath
 	redacted.Options = make([]string, 0)
 
 	parser.UnknownOptionHandler = func(
@@ -209,7 +314,7 @@ func RedactCommand(shellType shell.Type, lines []string) *RedactedCommand {
 		redacted.Options = append(redacted.Options, option)
 		return args, nil
 	}
-	_, err = parser.ParseArgs(splitLine[argsIdx:])
+	_, err := parser.ParseArgs(args)
 	if err != nil {
 		log.Printf("Error parsing command line %v\n", err)
 		return nil
@@ -218,24 +323,13 @@ func RedactCommand(shellType shell.Type, lines []string) *RedactedCommand {
 	return redacted
 }
 
-// ParseLines takes 1 or 2 lines of history file and returns
-// the command line and timestamp, if one was present
-func ParseLines(shellType shell.Type, lines []string) (commandTime time.Time, command string) {
+// ParseLines takes the lines of a single history file entry (1 or 2 for bash, 1 for zsh,
+// PowerShell and nu, the cmd/when/paths block for fish) and returns the command line and timestamp,
+// if one was present, along with how long the command ran for formats that record it
+func ParseLines(shellType shell.Type, lines []string) (commandTime time.Time, duration time.Duration, command string) {
 	switch shellType {
 	case shell.Zsh:
-		// Split off the timestamp
-		res := ZshHistoryLineRegEx.FindStringSubmatch(lines[0])
-		if len(res) >= 2 {
-			timestampSecs, err := strconv.Atoi(res[1])
-			if err != nil {
-				return
-			}
-			commandTime = time.Unix(int64(timestampSecs), 0)
-			command = res[2]
-		} else {
-			// Assume this is the non-timestamped zsh format
-			command = lines[0]
-		}
+		commandTime, duration, command = parseZshLine(lines[0])
 
 	case shell.Bash:
 		if len(lines) == 1 {
@@ -249,23 +343,133 @@ func ParseLines(shellType shell.Type, lines []string) (commandTime time.Time, co
 			command = lines[1]
 		}
 	case shell.Fish:
-		log.Println()
+		commandTime, command = parseFishLines(lines)
+	case shell.PowerShell:
+		command = lines[0]
+	case shell.Nu:
+		command = parseNuLine(lines[0])
 	}
 	return
 }
 
+// splitCommandLine splits a command line into words using the quoting rules of the shell
+func splitCommandLine(shellType shell.Type, commandLine string) ([]string, error) {
+	if shellType == shell.Nu {
+		return splitNuCommand(commandLine)
+	}
+	return shellquote.Split(commandLine)
+}
+
+// redactDirectory replaces each component of a directory path with *, keeping
+// only whether it was under the home directory and how deep it was.
+func redactDirectory(dir string) string {
+	if len(dir) == 0 {
+		return ""
+	}
+	prefix := "/"
+	home := os.ExpandEnv("$HOME")
+	if len(home) > 0 && (dir == home || strings.HasPrefix(dir, home+"/")) {
+		prefix = "~/"
+		dir = dir[len(home):]
+	}
+	components := make([]string, 0)
+	for _, component := range strings.Split(dir, "/") {
+		if len(component) > 0 {
+			components = append(components, "*")
+		}
+	}
+	redacted := prefix + strings.Join(components, "/")
+	if len(redacted) > 1 {
+		redacted = strings.TrimSuffix(redacted, "/")
+	}
+	return redacted
+}
+
+// CommandCount is how many times a command was used
+type CommandCount struct {
+	Command string
+	Count   int
+}
+
+// CommandCounts returns how many times each command was used, most used first
+func (h *ShellHistory) CommandCounts() []CommandCount {
+	countsByCommand := map[string]int{}
+	for _, r := range h.RedactedLines {
+		if r != nil {
+			countsByCommand[r.Command]++
+		}
+	}
+	return sortCommandCounts(countsByCommand)
+}
+
+// sortCommandCounts returns the count of each command, most used first
+func sortCommandCounts(countsByCommand map[string]int) []CommandCount {
+	counts := make([]CommandCount, 0, len(countsByCommand))
+	for command, count := range countsByCommand {
+		counts = append(counts, CommandCount{Command: command, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Command < counts[j].Command
+	})
+	return counts
+}
+
+// UnknownSubcommands returns the number of commands with a subcommand that wasn't in
+// the registry and was replaced by UnknownSubcommand
+func (h *ShellHistory) UnknownSubcommands() int {
+	unknown := 0
+	for _, r := range h.RedactedLines {
+		if r != nil && r.HasUnknownSubcommand() {
+			unknown++
+		}
+	}
+	return unknown
+}
+
+// MaskedSecrets counts the options whose values were masked because they looked like
+// secrets, by the kind of secret
+func (h *ShellHistory) MaskedSecrets() map[string]int {
+	masked := map[string]int{}
+	for _, r := range h.RedactedLines {
+		if r == nil {
+			continue
+		}
+		for _, secret := range r.MaskedSecrets {
+			masked[secret]++
+		}
+	}
+	return masked
+}
+
+// HasUnknownSubcommand returns true if one of the command's subcommands wasn't in the
+// registry and was replaced by UnknownSubcommand
+func (r *RedactedCommand) HasUnknownSubcommand() bool {
+	for _, subcommand := range r.SubcommandPath {
+		if subcommand == UnknownSubcommand {
+			return true
+		}
+	}
+	return false
+}
+
 // Preview returns a single line preview of a command suitable for showing a user.
 func (r *RedactedCommand) Preview() string {
-	preview := r.Command + " " + r.Subcommand
+	preview := ""
+	for _, envVar := range r.EnvVars {
+		preview += envVar + "=* "
+	}
+	for _, wrapper := range r.Wrappers {
+		preview += wrapper + " "
+	}
+	preview += r.Command + " " + strings.Join(r.SubcommandPath, " ")
 	if len(r.Options) > 0 {
 		preview += " [flags: " + strings.Join(r.Options, ",") + "]"
 	}
+	if len(r.Operator) > 0 {
+		preview += " " + r.Operator
+	}
 	return preview
 }
-
-func getSha1Hex(line string) string {
-	h := sha1.New()
-	h.Write([]byte(line))
-	bs := h.Sum(nil)
-	return fmt.Sprintf("%x", bs)
-}
@@ -1,6 +1,9 @@
 package history
 
 import (
+	"bufio"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -50,10 +53,14 @@ func TestRedactCommandFlagWithParam(t *testing.T) {
 	assert.Equal(t, 1, len(r.Options))
 }
 
-func TestRedactCommandSha1Equal(t *testing.T) {
+func TestRedactCommandHashEqual(t *testing.T) {
 	r1 := RedactCommand(shell.Bash, []string{"ls --foo=bar"})
 	r2 := RedactCommand(shell.Bash, []string{"ls --foo=bar"})
-	assert.Equal(t, r1.Sha1, r2.Sha1)
+	assert.Equal(t, r1.Hash, r2.Hash)
+	assert.Len(t, r1.Hash, 64)
+
+	r3 := RedactCommand(shell.Bash, []string{"ls --foo=baz"})
+	assert.NotEqual(t, r1.Hash, r3.Hash)
 }
 
 func TestRedactCommandBashTimestamps(t *testing.T) {
@@ -75,11 +82,63 @@ func TestRedactCommandUnparseable(t *testing.T) {
 func TestParseZshLineBasics(t *testing.T) {
 	res := ZshHistoryLineRegEx.FindStringSubmatch(": 1584112360:0;ls")
 	assert.Equal(t, res[1], "1584112360")
-	assert.Equal(t, res[2], "ls")
+	assert.Equal(t, res[2], "0")
+	assert.Equal(t, res[3], "ls")
 }
 
 func TestParseZshLineBasics2(t *testing.T) {
 	res := ZshHistoryLineRegEx.FindStringSubmatch(": 1589913271:0;history | grep export")
 	assert.Equal(t, res[1], "1589913271")
-	assert.Equal(t, res[2], "history | grep export")
+	assert.Equal(t, res[3], "history | grep export")
+}
+
+func TestRedactHistoryFileCustomName(t *testing.T) {
+	file, err := ioutil.TempFile("", ".histfile")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(": 1584112360:0;ls\n: 1584112361:0;git status\n")
+	file.Close()
+
+	fileName := file.Name()
+	history := RedactHistoryFile(&fileName, shell.Zsh)
+	assert.NotNil(t, history)
+	assert.Equal(t, 2, len(history.RedactedLines))
+	assert.Nil(t, RedactHistoryFile(&fileName, shell.Bash))
+}
+
+func TestRedactHistoryFileWithoutMarkers(t *testing.T) {
+	// zsh without EXTENDED_HISTORY writes a command per line, like bash
+	file, err := ioutil.TempFile("", ".histfile")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("ls\ngit status\n")
+	file.Close()
+
+	fileName := file.Name()
+	history := RedactHistoryFile(&fileName, shell.Zsh)
+	assert.NotNil(t, history)
+	assert.Equal(t, shell.Type(shell.Zsh), history.ShellType)
+	assert.Equal(t, []string{"ls", "git"}, commandNames(history))
+}
+
+func TestRedactHistoryFileBashBackticks(t *testing.T) {
+	file, err := ioutil.TempFile("", ".bash_history")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("kill `cat /tmp/pid`\necho `date`\nGet-ChildItem\n")
+	file.Close()
+
+	fileName := file.Name()
+	history := RedactHistoryFile(&fileName, shell.Bash)
+	assert.NotNil(t, history)
+	assert.Equal(t, []string{"kill", "echo", "Get-ChildItem"}, commandNames(history))
+}
+
+// readAllEntries reads every entry of a history with the given reader
+func readAllEntries(read historyReader, reader *bufio.Reader) ([][]string, int, error) {
+	entries := make([][]string, 0)
+	unattributed, err := read(reader, func(entry []string) {
+		entries = append(entries, entry)
+	})
+	return entries, unattributed, err
 }
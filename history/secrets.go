@@ -0,0 +1,209 @@
+package history
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// Kinds of secret we look for, used to explain what was suppressed
+const (
+	secretAWSAccessKey = "AWS access key"
+	secretAWSSecretKey = "AWS secret key"
+	secretGitHubToken  = "GitHub token"
+	secretGitLabToken  = "GitLab token"
+	secretSlackToken   = "Slack token"
+	secretJWT          = "JSON web token"
+	secretPrivateKey   = "private key"
+	secretURLPassword  = "password in a URL"
+	secretPasswordFlag = "password flag"
+	secretHighEntropy  = "random-looking string"
+	secretGluedValue   = "value glued to a short flag"
+)
+
+// Random-looking strings are at least this long and have at least this many bits of
+// entropy per character
+const (
+	minHighEntropyLength = 24
+	minHighEntropyBits   = 3.75
+)
+
+// maxShortFlagCluster is the longest run of short flags we expect after a single
+// dash, like -xzvf, from a command we have no flag rules for. Anything longer is
+// taken to be a flag with a value glued on, like redis-cli -aMyPassword.
+const maxShortFlagCluster = 6
+
+// secretRule matches a kind of secret with a known format
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Secrets with a known format. A history line containing one of these is dropped.
+var secretRules = []secretRule{
+	{secretAWSAccessKey, regexp.MustCompile(`\b(AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{secretAWSSecretKey, regexp.MustCompile(`(?i)aws.{0,20}secret.{0,20}[=:]\s*['"]?[A-Za-z0-9/+=]{40}`)},
+	{secretGitHubToken, regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{secretGitLabToken, regexp.MustCompile(`\bgl(pat|dt|rt|ptt|cbt|soat)-[A-Za-z0-9_\-]{20,}`)},
+	{secretSlackToken, regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{secretJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	{secretPrivateKey, regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{secretURLPassword, regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// passwordFlagRegEx matches the names of options whose value is a credential
+var passwordFlagRegEx = regexp.MustCompile(`(?i)^-{1,2}([a-z0-9]+[-_])*(pass|passwd|password|secret|token|api[-_]?key|auth[-_]?token|access[-_]?key|credentials?)$`)
+
+// gluedPasswordFlags are short options, by command, that take a password or
+// user:password which can be glued on, like mysql -pHunter2
+var gluedPasswordFlags = map[string][]string{
+	"mysql":       {"-p"},
+	"mysqldump":   {"-p"},
+	"mysqladmin":  {"-p"},
+	"mysqlimport": {"-p"},
+	"mariadb":     {"-p"},
+	"sshpass":     {"-p"},
+	"curl":        {"-u"},
+	"smbclient":   {"-U"},
+}
+
+// highEntropyRegEx finds candidates for random-looking strings like API keys. Words
+// are split at -, _ and / first, so branch and file names like
+// feature/JIRA-1234-fixLoginBug aren't mistaken for one.
+var highEntropyRegEx = regexp.MustCompile(`[A-Za-z0-9+=]{24,}`)
+
+var highEntropySeparators = strings.NewReplacer("-", " ", "_", " ", "/", " ")
+
+// findSecret returns the kind of secret in a command line, or an empty string if it
+// doesn't look like it holds one. Options aren't checked for random-looking strings,
+// those are masked by maskSecretOptions instead of dropping the whole line.
+func findSecret(commandLine string) string {
+	for _, rule := range secretRules {
+		if rule.pattern.MatchString(commandLine) {
+			return rule.name
+		}
+	}
+	for _, word := range strings.Fields(commandLine) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		if containsHighEntropyString(word) {
+			return secretHighEntropy
+		}
+	}
+	return ""
+}
+
+// maskSecretOptions strips values that look like secrets from a command's options,
+// e.g. mysql -pHunter2 becomes mysql -p, so they can't end up in the option names.
+// Returns the masked words and the kind of secret behind each option it masked.
+// PowerShell's options are whole words, like -ErrorAction, so they're never taken
+// for short flags with a value glued on.
+func maskSecretOptions(shellType shell.Type, words []string) ([]string, []string) {
+	masked := make([]string, 0, len(words))
+	reasons := make([]string, 0)
+	for i, word := range words {
+		if i == 0 || len(word) < 3 || !strings.HasPrefix(word, "-") || word == "--" {
+			masked = append(masked, word)
+			continue
+		}
+		maskedWord, reason := maskSecretOption(words[0], word, shellType != shell.PowerShell)
+		if len(reason) > 0 {
+			reasons = append(reasons, reason)
+		}
+		masked = append(masked, maskedWord)
+	}
+	return masked, reasons
+}
+
+func maskSecretOption(command string, option string, shortFlags bool) (string, string) {
+	if eq := strings.Index(option, "="); eq > 0 && passwordFlagRegEx.MatchString(option[:eq]) {
+		return option[:eq], secretPasswordFlag
+	}
+	if strings.HasPrefix(option, "--") {
+		// go-flags drops the value of long options
+		return option, ""
+	}
+	for _, flag := range gluedPasswordFlags[command] {
+		if strings.HasPrefix(option, flag) && len(option) > len(flag) {
+			return flag, secretPasswordFlag
+		}
+	}
+	for _, rule := range secretRules {
+		if rule.pattern.MatchString(option) {
+			return option[:2], rule.name
+		}
+	}
+	if containsHighEntropyString(option[2:]) {
+		return option[:2], secretHighEntropy
+	}
+	if shortFlags && hasGluedValue(command, option) {
+		return option[:2], secretGluedValue
+	}
+	return option, ""
+}
+
+// hasGluedValue returns true if a single dash option of a command we have no flag
+// rules for is too long to be a cluster of short flags. Single dash long options
+// like find -maxdepth are lowercase words, so they're left alone.
+func hasGluedValue(command string, option string) bool {
+	if _, ok := flagGlueRules[command]; ok {
+		return false
+	}
+	name := option[1:]
+	if eq := strings.Index(name, "="); eq >= 0 {
+		name = name[:eq]
+	}
+	if len(name) <= maxShortFlagCluster {
+		return false
+	}
+	for _, c := range name[1:] {
+		if !unicode.IsLower(c) && c != '-' {
+			return true
+		}
+	}
+	return false
+}
+
+// containsHighEntropyString returns true if s contains a long run of characters that
+// looks random, mixing upper and lower case letters and digits like an API key does.
+// Hex strings like commit hashes don't count.
+func containsHighEntropyString(s string) bool {
+	for _, candidate := range highEntropyRegEx.FindAllString(highEntropySeparators.Replace(s), -1) {
+		if len(candidate) < minHighEntropyLength {
+			continue
+		}
+		upper, lower, digits := 0, 0, 0
+		for _, c := range candidate {
+			switch {
+			case unicode.IsUpper(c):
+				upper++
+			case unicode.IsLower(c):
+				lower++
+			case unicode.IsDigit(c):
+				digits++
+			}
+		}
+		if upper >= 2 && lower >= 2 && digits >= 2 && shannonEntropy(candidate) >= minHighEntropyBits {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, c := range s {
+		counts[c]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,100 @@
+package history
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// sessionHistoryPattern matches the history files in a sessions directory. macOS
+// Terminal names them after the session, e.g. 0F3C1E2A-....history, next to a .session
+// file it uses to restore the window and a .historynew file while the shell is open.
+const sessionHistoryPattern = "*.history"
+
+// RedactHistoryDir redacts every history file in a directory that keeps a file per
+// terminal session, like the ~/.bash_sessions and ~/.zsh_sessions directories macOS
+// Terminal writes, and merges them into a single history. Every file is read as the
+// given shell's history, and each command is tagged with the session it ran in, the
+// name of its file. Returns nil if the directory has no history we can read.
+func RedactHistoryDir(dir string, shellType shell.Type) *ShellHistory {
+	log.Println("Reading history directory", dir)
+	if historyReaders[shellType] == nil {
+		log.Println("Unable to read", shellType, "history")
+		return nil
+	}
+	files, err := sessionHistoryFiles(dir)
+	if err != nil {
+		log.Println("Error reading history directory", err)
+		return nil
+	}
+
+	histories := make([]*ShellHistory, 0, len(files))
+	for _, file := range files {
+		historyFile, err := os.Open(file)
+		if err != nil {
+			log.Println("Error reading history file, skipping.", file)
+			continue
+		}
+		history := redactOpenHistoryFile(historyFile, shellType)
+		historyFile.Close()
+		if history == nil {
+			continue
+		}
+		session := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		for _, r := range history.RedactedLines {
+			if r != nil {
+				r.Session = session
+			}
+		}
+		histories = append(histories, history)
+	}
+
+	// Sessions are merged oldest first, so commands without timestamps stay in the
+	// order the sessions ended
+	merged := MergeHistories(histories)
+	if merged == nil {
+		return nil
+	}
+	merged.FileName = dir
+	return merged
+}
+
+// RedactHistoryCandidate redacts a history file or sessions directory found by
+// DiscoverHistoryFiles
+func RedactHistoryCandidate(candidate HistoryCandidate) *ShellHistory {
+	if candidate.Sessions {
+		return RedactHistoryDir(candidate.Path, candidate.ShellType)
+	}
+	path := candidate.Path
+	return RedactHistoryFile(&path, candidate.ShellType)
+}
+
+// sessionHistoryFiles returns the non-empty session history files in a directory,
+// least recently modified first
+func sessionHistoryFiles(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, sessionHistoryPattern))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(matches))
+	infos := map[string]os.FileInfo{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+			continue
+		}
+		files = append(files, match)
+		infos[match] = info
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return infos[files[i]].ModTime().Before(infos[files[j]].ModTime())
+	})
+	return files, nil
+}
@@ -0,0 +1,71 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestRedactHistoryDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bash_sessions")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "B.history"), "make\ngit status\n")
+	writeTestFile(t, filepath.Join(dir, "A.history"), "ls\n")
+	writeTestFile(t, filepath.Join(dir, "A.historynew"), "pwd\n")
+	writeTestFile(t, filepath.Join(dir, "A.session"), "echo Restored session\n")
+	writeTestFile(t, filepath.Join(dir, "C.history"), "")
+	now := time.Now()
+	assert.Nil(t, os.Chtimes(filepath.Join(dir, "A.history"), now.Add(-time.Hour), now.Add(-time.Hour)))
+	assert.Nil(t, os.Chtimes(filepath.Join(dir, "B.history"), now, now))
+
+	history := RedactHistoryDir(dir, shell.Bash)
+	assert.NotNil(t, history)
+	assert.Equal(t, dir, history.FileName)
+	assert.Equal(t, []string{"ls", "make", "git"}, commandNames(history))
+	sessions := make([]string, 0)
+	for _, r := range history.RedactedLines {
+		sessions = append(sessions, r.Session)
+	}
+	assert.Equal(t, []string{"A", "B", "B"}, sessions)
+	fileName, shellType := history.CommandSource(history.RedactedLines[1])
+	assert.Equal(t, filepath.Join(dir, "B.history"), fileName)
+	assert.Equal(t, shell.Type(shell.Bash), shellType)
+}
+
+func TestRedactHistoryDirMergesByTimestamp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zsh_sessions")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Sessions overlap when several windows are open, and without EXTENDED_HISTORY
+	// zsh writes a plain command per line
+	writeTestFile(t, filepath.Join(dir, "A.history"), ": 1584112360:0;ls\n: 1584112380:0;make\n")
+	writeTestFile(t, filepath.Join(dir, "B.history"), ": 1584112370:0;git status\n")
+	writeTestFile(t, filepath.Join(dir, "C.history"), "pwd\n")
+
+	history := RedactHistoryDir(dir, shell.Zsh)
+	assert.NotNil(t, history)
+	assert.Equal(t, 4, len(history.RedactedLines))
+	assert.Equal(t, "pwd", history.RedactedLines[0].Command)
+	assert.Equal(t, "C", history.RedactedLines[0].Session)
+	assert.Equal(t, []string{"ls", "git", "make"}, commandNames(history)[1:])
+	assert.Equal(t, "B", history.RedactedLines[2].Session)
+}
+
+func TestRedactHistoryDirWithoutHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, RedactHistoryDir(dir, shell.Bash))
+	assert.Nil(t, RedactHistoryDir(filepath.Join(dir, "missing"), shell.Bash))
+	writeTestFile(t, filepath.Join(dir, "A.history"), "ls\n")
+	assert.Nil(t, RedactHistoryDir(dir, shell.Unknown))
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Separators used when reading query results from the sqlite3 command line tool.
+// Commands can contain tabs and newlines, so use the ASCII unit and record separators.
+const (
+	sqliteColumnSeparator = "\x1f"
+	sqliteRowSeparator    = "\x1e"
+)
+
+// Every SQLite database file starts with this header
+const sqliteHeader = "SQLite format 3\x00"
+
+// isSQLiteFile returns true if the file is a SQLite database, leaving the file
+// offset back at the start.
+func isSQLiteFile(file *os.File) bool {
+	header := make([]byte, len(sqliteHeader))
+	_, err := io.ReadFull(file, header)
+	file.Seek(0, io.SeekStart)
+	return err == nil && string(header) == sqliteHeader
+}
+
+// sqliteAvailable returns true if the sqlite3 command line tool is installed.
+// We shell out to it rather than linking a sqlite library so the survey binary
+// can still be cross-compiled without cgo.
+func sqliteAvailable() bool {
+	_, err := exec.LookPath("sqlite3")
+	return err == nil
+}
+
+// querySQLite runs a read-only query against the database at dbPath and returns
+// the rows as columns of text. NULL values are returned as empty strings.
+func querySQLite(dbPath string, query string) ([][]string, error) {
+	if !sqliteAvailable() {
+		return nil, errors.New("sqlite3 is not installed")
+	}
+	cmd := exec.Command("sqlite3", "-readonly", "-batch", "-noheader",
+		"-separator", sqliteColumnSeparator, "-newline", sqliteRowSeparator, dbPath, query)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.New("sqlite3 query failed: " + strings.TrimSpace(stderr.String()))
+	}
+
+	rows := make([][]string, 0)
+	for _, row := range strings.Split(out.String(), sqliteRowSeparator) {
+		if len(row) > 0 {
+			rows = append(rows, strings.Split(row, sqliteColumnSeparator))
+		}
+	}
+	return rows, nil
+}
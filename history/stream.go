@@ -0,0 +1,243 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// redactionWorkers is how many history entries are redacted in parallel
+var redactionWorkers = runtime.NumCPU()
+
+// redactionsPerWorker is how many redacted entries each worker can get ahead of the
+// consumer of a stream
+const redactionsPerWorker = 64
+
+// Redaction is a single history file entry after redaction
+type Redaction struct {
+	// Commands are the redacted commands in the entry, in the order they appear
+	Commands []*RedactedCommand
+
+	// Secret is the kind of secret the entry looked like it held, if it was dropped
+	// rather than redacted
+	Secret string
+}
+
+// HistoryStream is a history file being read and redacted in parallel, a few entries
+// at a time. Redactions receives each entry in the order it appears in the file, and
+// is closed once the whole file has been read or the stream is stopped.
+type HistoryStream struct {
+	FileName   string
+	ShellType  shell.Type
+	Redactions <-chan Redaction
+
+	stopped  chan struct{}
+	stopOnce sync.Once
+	finished chan struct{}
+
+	unattributed int
+	err          error
+}
+
+// Wait returns the number of lines that couldn't be attributed to a command, and any
+// error reading the file, once it has been read to the end. Redactions must be
+// drained first, or the stream stopped.
+func (s *HistoryStream) Wait() (int, error) {
+	<-s.finished
+	return s.unattributed, s.err
+}
+
+// Stop stops reading and redacting the file, so a consumer can stop reading
+// Redactions without leaving the reader and workers blocked
+func (s *HistoryStream) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+	})
+}
+
+// StreamHistoryFile starts reading and redacting a history file of the given shell
+// type without holding the whole file in memory. The shell is detected the same way as
+// in RedactHistoryFile. Returns an error if the file can't be opened or was written by
+// a different shell. nu's SQLite history can't be streamed, use RedactHistoryFile
+// instead.
+func StreamHistoryFile(historyFilePath string, targetShellType shell.Type) (*HistoryStream, error) {
+	historyFile, err := os.Open(historyFilePath)
+	if err != nil {
+		return nil, err
+	}
+	shellType, confidence := shell.DetectShellType(historyFile.Name())
+	log.Println("History file looks like", shellType, "history, confidence", confidence)
+	if shellType == shell.Unknown || confidence < 0.5 {
+		// Nothing in the file says which shell wrote it, so trust the caller
+		shellType = targetShellType
+	}
+	if shellType != targetShellType || historyReaders[shellType] == nil {
+		historyFile.Close()
+		return nil, errors.New("Not a " + string(targetShellType) + " history file")
+	}
+	if shellType == shell.Nu && isSQLiteFile(historyFile) {
+		historyFile.Close()
+		return nil, errors.New("Can't stream a history database")
+	}
+	return streamHistory(historyFile, historyFile.Name(), shellType), nil
+}
+
+// CommandCounts reads the rest of the stream and returns how many times each command
+// was used, most used first, like ShellHistory.CommandCounts
+func (s *HistoryStream) CommandCounts() ([]CommandCount, error) {
+	countsByCommand := map[string]int{}
+	for redaction := range s.Redactions {
+		for _, r := range redaction.Commands {
+			countsByCommand[r.Command]++
+		}
+	}
+	if _, err := s.Wait(); err != nil {
+		return nil, err
+	}
+	return sortCommandCounts(countsByCommand), nil
+}
+
+// FirstTimestamp reads the rest of the stream and returns the earliest time a command
+// ran, like ShellHistory.FirstTimestamp
+func (s *HistoryStream) FirstTimestamp() (time.Time, error) {
+	var first time.Time
+	for redaction := range s.Redactions {
+		for _, r := range redaction.Commands {
+			first = earlierTimestamp(first, r.Timestamp)
+		}
+	}
+	_, err := s.Wait()
+	return first, err
+}
+
+// streamHistory reads history entries from reader with the reader for the shell type
+// and redacts them in parallel, closing the reader once it has been read
+func streamHistory(reader io.ReadCloser, fileName string, shellType shell.Type) *HistoryStream {
+	entries := make(chan []string, redactionWorkers)
+	stream := &HistoryStream{
+		FileName:  fileName,
+		ShellType: shellType,
+		stopped:   make(chan struct{}),
+		finished:  make(chan struct{}),
+	}
+	stream.Redactions = redactEntries(shellType, entries, redactionWorkers, stream.stopped)
+	go func() {
+		defer close(stream.finished)
+		defer reader.Close()
+		defer close(entries)
+		// Once stopped the rest of the file is read, but not redacted
+		stream.unattributed, stream.err = historyReaders[shellType](bufio.NewReader(reader), func(entry []string) {
+			select {
+			case entries <- entry:
+			case <-stream.stopped:
+			}
+		})
+	}()
+	return stream
+}
+
+// redactEntries redacts history entries with a pool of workers, returning a channel
+// that receives each redacted entry in the order the entries were sent. It is closed
+// once entries is closed and everything sent on it has been redacted, or once stopped
+// is closed, when the workers stop too.
+func redactEntries(shellType shell.Type, entries <-chan []string, workers int,
+	stopped <-chan struct{}) <-chan Redaction {
+	if workers < 1 {
+		workers = 1
+	}
+	type job struct {
+		lines  []string
+		result chan Redaction
+	}
+	jobs := make(chan job, workers)
+	// Results are queued in the order the entries arrived, whichever worker
+	// finishes first
+	pending := make(chan chan Redaction, workers*redactionsPerWorker)
+	redactions := make(chan Redaction, workers)
+
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for {
+			var lines []string
+			select {
+			case entry, ok := <-entries:
+				if !ok {
+					return
+				}
+				lines = entry
+			case <-stopped:
+				return
+			}
+			result := make(chan Redaction, 1)
+			select {
+			case pending <- result:
+			case <-stopped:
+				return
+			}
+			select {
+			case jobs <- job{lines: lines, result: result}:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				j.result <- redactHistoryEntry(shellType, j.lines)
+			}
+		}()
+	}
+	go func() {
+		defer close(redactions)
+		for result := range pending {
+			select {
+			case redaction := <-result:
+				select {
+				case redactions <- redaction:
+				case <-stopped:
+					return
+				}
+			case <-stopped:
+				return
+			}
+		}
+	}()
+	return redactions
+}
+
+// redactHistoryEntry redacts a single entry of a history file, or drops it if it
+// looks like it holds a secret
+func redactHistoryEntry(shellType shell.Type, lines []string) Redaction {
+	commandTime, duration, commandLine := ParseLines(shellType, lines)
+	return redactParsedEntry(shellType, commandTime, duration, commandLine)
+}
+
+// redactParsedEntry redacts a history entry that has already been parsed, or drops it
+// if it looks like it holds a secret
+func redactParsedEntry(shellType shell.Type, commandTime time.Time, duration time.Duration,
+	commandLine string) Redaction {
+	if secret := findSecret(commandLine); len(secret) > 0 {
+		return Redaction{Secret: secret}
+	}
+	return Redaction{Commands: redactCommandLine(shellType, commandTime, duration, commandLine)}
+}
+
+// Add adds the commands of a redacted entry to the history, or counts it in
+// SuppressedLines if it was dropped, and returns the commands added
+func (h *ShellHistory) Add(redaction Redaction) []*RedactedCommand {
+	if len(redaction.Secret) > 0 {
+		h.SuppressedLines[redaction.Secret]++
+		return nil
+	}
+	h.RedactedLines = append(h.RedactedLines, redaction.Commands...)
+	return redaction.Commands
+}
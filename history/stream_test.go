@@ -0,0 +1,103 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestRedactEntriesKeepsOrder(t *testing.T) {
+	entries := make(chan []string)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			entries <- []string{"git commit -m " + strconv.Itoa(i)}
+			entries <- []string{"ls -la " + strconv.Itoa(i)}
+		}
+		close(entries)
+	}()
+
+	i := 0
+	for redaction := range redactEntries(shell.Bash, entries, 8, make(chan struct{})) {
+		assert.Equal(t, 1, len(redaction.Commands))
+		if i%2 == 0 {
+			assert.Equal(t, "git", redaction.Commands[0].Command)
+		} else {
+			assert.Equal(t, "ls", redaction.Commands[0].Command)
+		}
+		i++
+	}
+	assert.Equal(t, 2000, i)
+}
+
+func TestStreamHistoryFile(t *testing.T) {
+	file, err := ioutil.TempFile("", ".bash_history")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("#1584112360\n#1584112361\nls\nexport GITHUB_TOKEN=" + fakeGitHubToken + "\ngit status\n")
+	file.Close()
+
+	stream, err := StreamHistoryFile(file.Name(), shell.Bash)
+	assert.Nil(t, err)
+	h := newShellHistory(stream.FileName, stream.ShellType)
+	for redaction := range stream.Redactions {
+		h.Add(redaction)
+	}
+	unattributed, err := stream.Wait()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, unattributed)
+	assert.Equal(t, []string{"ls", "git"}, commandNames(h))
+	assert.Equal(t, map[string]int{secretGitHubToken: 1}, h.SuppressedLines)
+
+	stream, err = StreamHistoryFile(file.Name(), shell.Bash)
+	assert.Nil(t, err)
+	counts, err := stream.CommandCounts()
+	assert.Nil(t, err)
+	assert.Equal(t, []CommandCount{{Command: "git", Count: 1}, {Command: "ls", Count: 1}}, counts)
+
+	stream, err = StreamHistoryFile(file.Name(), shell.Bash)
+	assert.Nil(t, err)
+	first, err := stream.FirstTimestamp()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1584112361, 0), first)
+
+	_, err = StreamHistoryFile(file.Name(), shell.Zsh)
+	assert.NotNil(t, err)
+	_, err = StreamHistoryFile(file.Name()+".missing", shell.Bash)
+	assert.NotNil(t, err)
+}
+
+func TestStreamHistoryStop(t *testing.T) {
+	file, err := ioutil.TempFile("", ".bash_history")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	for i := 0; i < 10000; i++ {
+		file.WriteString("git commit -m " + strconv.Itoa(i) + "\n")
+	}
+	file.Close()
+
+	goroutines := runtime.NumGoroutine()
+	historyFile, err := os.Open(file.Name())
+	assert.Nil(t, err)
+	stream := streamHistory(historyFile, historyFile.Name(), shell.Bash)
+	<-stream.Redactions
+	stream.Stop()
+	stream.Stop()
+
+	// The file is read to the end without being redacted, and nothing is left running
+	unattributed, err := stream.Wait()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, unattributed)
+	for range stream.Redactions {
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
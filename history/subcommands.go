@@ -0,0 +1,605 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+// SubcommandTree describes the subcommands of a tool, or of one of its subcommands.
+// Registries are written as JSON objects mapping tool names to their trees, e.g.
+//
+//	{"docker": {"valueFlags": ["-H", "--context"],
+//	            "subcommands": {"compose": {"leaves": ["up", "down"]}},
+//	            "leaves": ["run", "ps"]}}
+type SubcommandTree struct {
+	// ValueFlags are options accepted at this level that take a separate value,
+	// which must be skipped so the value isn't mistaken for a subcommand
+	ValueFlags []string `json:"valueFlags,omitempty"`
+
+	// Subcommands maps subcommand names to their own trees
+	Subcommands map[string]*SubcommandTree `json:"subcommands,omitempty"`
+
+	// Leaves are subcommands with no subcommands of their own, a shorthand
+	// for listing them in Subcommands with an empty tree
+	Leaves []string `json:"leaves,omitempty"`
+
+	// AnySubcommand means the first word at this level is always a subcommand, e.g.
+	// a git alias or a yarn script. Words that aren't listed are recorded as
+	// UnknownSubcommand rather than as themselves, so arguments never leak.
+	AnySubcommand bool `json:"anySubcommand,omitempty"`
+
+	// SkipPrefixes are prefixes of words that aren't subcommands or options and
+	// should be skipped, like the toolchain in cargo +nightly build
+	SkipPrefixes []string `json:"skipPrefixes,omitempty"`
+}
+
+// UnknownSubcommand stands in for a subcommand that isn't in the registry
+const UnknownSubcommand = "<unknown-subcommand>"
+
+// subcommandRegistry is the registry of tools we know have subcommands
+var subcommandRegistry = mustParseSubcommandRegistry(defaultSubcommandRegistry)
+
+// LoadSubcommandRegistry reads a JSON subcommand registry from a file and merges it
+// into the built in one, so teams can describe their own tools without recompiling.
+func LoadSubcommandRegistry(registryPath string) error {
+	b, err := ioutil.ReadFile(registryPath)
+	if err != nil {
+		return err
+	}
+	registry, err := parseSubcommandRegistry(b)
+	if err != nil {
+		return err
+	}
+	for tool, tree := range registry {
+		if existing, ok := subcommandRegistry[tool]; ok {
+			existing.merge(tree)
+		} else {
+			subcommandRegistry[tool] = tree
+		}
+	}
+	return nil
+}
+
+func parseSubcommandRegistry(b []byte) (map[string]*SubcommandTree, error) {
+	registry := map[string]*SubcommandTree{}
+	if err := json.Unmarshal(b, &registry); err != nil {
+		return nil, err
+	}
+	for tool, tree := range registry {
+		if err := checkSubcommandTree(tool, tree); err != nil {
+			return nil, err
+		}
+		tree.expandLeaves()
+	}
+	return registry, nil
+}
+
+// checkSubcommandTree returns an error naming the first tree under path, e.g. git
+// remote, that is null rather than an object
+func checkSubcommandTree(path string, tree *SubcommandTree) error {
+	if tree == nil {
+		return errors.New("Subcommand registry entry for " + path + " must be an object, not null")
+	}
+	for name, subtree := range tree.Subcommands {
+		if err := checkSubcommandTree(path+" "+name, subtree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mustParseSubcommandRegistry(registryJSON string) map[string]*SubcommandTree {
+	registry, err := parseSubcommandRegistry([]byte(registryJSON))
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+// expandLeaves moves Leaves into Subcommands, recursively
+func (t *SubcommandTree) expandLeaves() {
+	if t.Subcommands == nil {
+		t.Subcommands = map[string]*SubcommandTree{}
+	}
+	for _, leaf := range t.Leaves {
+		if _, ok := t.Subcommands[leaf]; !ok {
+			t.Subcommands[leaf] = &SubcommandTree{Subcommands: map[string]*SubcommandTree{}}
+		}
+	}
+	t.Leaves = nil
+	for _, subtree := range t.Subcommands {
+		if subtree == nil {
+			continue
+		}
+		subtree.expandLeaves()
+	}
+}
+
+// merge adds the flags and subcommands of other to the tree
+func (t *SubcommandTree) merge(other *SubcommandTree) {
+	t.ValueFlags = append(t.ValueFlags, other.ValueFlags...)
+	t.SkipPrefixes = append(t.SkipPrefixes, other.SkipPrefixes...)
+	t.AnySubcommand = t.AnySubcommand || other.AnySubcommand
+	for name, subtree := range other.Subcommands {
+		if existing, ok := t.Subcommands[name]; ok && existing != nil && subtree != nil {
+			existing.merge(subtree)
+		} else if !ok {
+			t.Subcommands[name] = subtree
+		}
+	}
+}
+
+func (t *SubcommandTree) takesValue(flag string) bool {
+	for _, valueFlag := range t.ValueFlags {
+		if valueFlag == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *SubcommandTree) skipsWord(word string) bool {
+	for _, prefix := range t.SkipPrefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSubcommandPath walks the subcommand tree for the command in words[0] and
+// returns its subcommand path along with the words that remain once the
+// subcommands are removed, to be parsed for options. Only subcommands in the
+// registry are recorded, anything else becomes UnknownSubcommand or is left
+// as an argument.
+func findSubcommandPath(words []string) (path []string, rest []string) {
+	path = make([]string, 0)
+	tree := subcommandRegistry[words[0]]
+	if tree == nil {
+		return path, words[1:]
+	}
+
+	// Value flags accumulate as we go deeper, since tools accept their global
+	// flags after subcommands too
+	valueFlags := &SubcommandTree{ValueFlags: tree.ValueFlags}
+	rest = make([]string, 0, len(words))
+	i := 1
+	for ; i < len(words) && tree != nil && len(tree.Subcommands) > 0; i++ {
+		word := words[i]
+		if len(word) > 1 && strings.HasPrefix(word, "-") {
+			rest = append(rest, word)
+			if !strings.Contains(word, "=") && valueFlags.takesValue(word) && i+1 < len(words) {
+				i++
+				rest = append(rest, words[i])
+			}
+			continue
+		}
+		if tree.skipsWord(word) {
+			continue
+		}
+		subtree, ok := tree.Subcommands[word]
+		if !ok {
+			if !tree.AnySubcommand {
+				break
+			}
+			path = append(path, UnknownSubcommand)
+			tree = nil
+			continue
+		}
+		path = append(path, word)
+		tree = subtree
+		if tree != nil {
+			valueFlags.ValueFlags = append(valueFlags.ValueFlags, tree.ValueFlags...)
+		}
+	}
+	return path, append(rest, words[i:]...)
+}
+
+// defaultSubcommandRegistry describes common tools with subcommands
+const defaultSubcommandRegistry = `{
+  "git": {
+    "anySubcommand": true,
+    "valueFlags": ["-C", "-c", "--git-dir", "--work-tree", "--namespace", "--config-env"],
+    "subcommands": {
+      "remote": {"leaves": ["add", "remove", "rm", "rename", "set-url", "get-url", "show", "prune", "update"]},
+      "stash": {"leaves": ["push", "pop", "apply", "list", "show", "drop", "clear", "branch", "save"]},
+      "submodule": {"leaves": ["add", "init", "update", "status", "sync", "foreach", "deinit", "summary"]},
+      "worktree": {"leaves": ["add", "list", "remove", "prune", "move", "lock", "unlock", "repair"]},
+      "bisect": {"leaves": ["start", "good", "bad", "new", "old", "reset", "skip", "run", "log", "replay", "visualize"]},
+      "lfs": {"leaves": ["install", "track", "untrack", "pull", "push", "fetch", "ls-files", "status", "migrate", "prune"]},
+      "notes": {"leaves": ["add", "append", "copy", "edit", "show", "list", "remove", "prune"]},
+      "sparse-checkout": {"leaves": ["init", "list", "set", "add", "reapply", "disable"]}
+    },
+    "leaves": ["add", "am", "apply", "archive", "blame", "branch", "checkout", "cherry-pick", "clean", "clone",
+      "commit", "config", "describe", "diff", "fetch", "format-patch", "gc", "grep", "help", "init", "log",
+      "merge", "mv", "pull", "push", "rebase", "reflog", "reset", "restore", "revert", "rm", "shortlog",
+      "show", "status", "switch", "tag", "version", "cherry", "difftool", "mergetool", "rev-parse",
+      "ls-files", "ls-remote", "fsck", "prune", "request-pull", "range-diff", "whatchanged", "maintenance"]
+  },
+  "docker": {
+    "valueFlags": ["-H", "--host", "-c", "--context", "--config", "-l", "--log-level"],
+    "subcommands": {
+      "compose": {
+        "valueFlags": ["-f", "--file", "-p", "--project-name", "--env-file", "--profile", "--project-directory"],
+        "leaves": ["up", "down", "ps", "logs", "build", "pull", "push", "exec", "run", "restart", "stop",
+          "start", "config", "rm", "top", "images", "create", "kill", "pause", "unpause", "port", "version", "ls", "watch"]
+      },
+      "container": {"leaves": ["ls", "run", "start", "stop", "rm", "exec", "logs", "inspect", "prune", "kill",
+        "restart", "attach", "cp", "create", "stats", "top"]},
+      "image": {"leaves": ["ls", "build", "pull", "push", "rm", "prune", "inspect", "tag", "history", "save", "load"]},
+      "volume": {"leaves": ["ls", "create", "rm", "prune", "inspect"]},
+      "network": {"leaves": ["ls", "create", "rm", "prune", "inspect", "connect", "disconnect"]},
+      "system": {"leaves": ["prune", "df", "info", "events"]},
+      "buildx": {"leaves": ["build", "create", "use", "ls", "inspect", "rm", "bake", "imagetools", "prune", "du"]},
+      "context": {"leaves": ["ls", "use", "create", "rm", "inspect", "show", "update"]},
+      "builder": {"leaves": ["prune", "build", "ls"]}
+    },
+    "leaves": ["attach", "build", "commit", "cp", "create", "diff", "events", "exec", "export", "history",
+      "images", "import", "info", "inspect", "kill", "load", "login", "logout", "logs", "pause", "port", "ps",
+      "pull", "push", "rename", "restart", "rm", "rmi", "run", "save", "search", "start", "stats", "stop",
+      "tag", "top", "unpause", "update", "version", "wait"]
+  },
+  "docker-compose": {
+    "valueFlags": ["-f", "--file", "-p", "--project-name", "--env-file", "--profile", "--project-directory"],
+    "leaves": ["up", "down", "ps", "logs", "build", "pull", "push", "exec", "run", "restart", "stop",
+      "start", "config", "rm", "top", "images", "create", "kill", "pause", "unpause", "port", "version"]
+  },
+  "kubectl": {
+    "valueFlags": ["-n", "--namespace", "--context", "--kubeconfig", "--cluster", "--user", "-s", "--server",
+      "--token", "--as", "--request-timeout", "-v"],
+    "subcommands": {
+      "get": {"leaves": ["all", "pods", "pod", "po", "deployments", "deployment", "deploy", "services", "service",
+        "svc", "nodes", "node", "no", "namespaces", "namespace", "ns", "configmaps", "configmap", "cm", "secrets",
+        "secret", "ingress", "ingresses", "ing", "jobs", "job", "cronjobs", "cronjob", "statefulsets", "sts",
+        "daemonsets", "ds", "replicasets", "rs", "pv", "pvc", "events", "ev", "crd", "endpoints", "ep",
+        "serviceaccounts", "sa", "hpa", "roles", "rolebindings", "clusterroles", "clusterrolebindings"]},
+      "describe": {"leaves": ["pods", "pod", "po", "deployments", "deployment", "deploy", "services", "service",
+        "svc", "nodes", "node", "no", "namespaces", "namespace", "ns", "configmaps", "configmap", "cm", "secrets",
+        "secret", "ingress", "ing", "jobs", "job", "cronjobs", "cronjob", "statefulsets", "sts", "daemonsets",
+        "ds", "replicasets", "rs", "pv", "pvc", "hpa"]},
+      "delete": {"leaves": ["pods", "pod", "po", "deployments", "deployment", "deploy", "services", "service",
+        "svc", "namespaces", "namespace", "ns", "configmaps", "configmap", "cm", "secrets", "secret", "ingress",
+        "ing", "jobs", "job", "cronjobs", "cronjob", "statefulsets", "sts", "daemonsets", "ds", "pv", "pvc"]},
+      "rollout": {"leaves": ["status", "restart", "history", "undo", "pause", "resume"]},
+      "config": {"leaves": ["use-context", "get-contexts", "current-context", "view", "set-context",
+        "set-cluster", "set-credentials", "delete-context", "rename-context", "get-clusters", "use"]},
+      "top": {"leaves": ["pods", "pod", "nodes", "node"]},
+      "auth": {"leaves": ["can-i", "whoami", "reconcile"]},
+      "create": {"leaves": ["namespace", "deployment", "service", "secret", "configmap", "job", "cronjob",
+        "serviceaccount", "role", "rolebinding", "clusterrole", "clusterrolebinding", "ingress", "token"]}
+    },
+    "leaves": ["apply", "logs", "exec", "port-forward", "scale", "cp", "run", "expose", "label", "annotate",
+      "patch", "edit", "explain", "version", "cluster-info", "drain", "cordon", "uncordon", "taint", "wait",
+      "diff", "kustomize", "proxy", "attach", "api-resources", "api-versions", "certificate", "set",
+      "autoscale", "debug", "events", "plugin", "completion", "replace"]
+  },
+  "helm": {
+    "valueFlags": ["-n", "--namespace", "--kube-context", "--kubeconfig"],
+    "subcommands": {
+      "search": {"leaves": ["repo", "hub"]},
+      "repo": {"leaves": ["add", "update", "list", "remove", "index"]},
+      "dependency": {"leaves": ["update", "build", "list"]},
+      "dep": {"leaves": ["update", "build", "list"]},
+      "show": {"leaves": ["all", "chart", "readme", "values", "crds"]},
+      "get": {"leaves": ["all", "hooks", "manifest", "notes", "values", "metadata"]},
+      "plugin": {"leaves": ["install", "list", "uninstall", "update"]},
+      "registry": {"leaves": ["login", "logout"]}
+    },
+    "leaves": ["install", "upgrade", "uninstall", "delete", "list", "ls", "status", "rollback", "history",
+      "template", "lint", "package", "pull", "push", "create", "env", "version", "test", "verify"]
+  },
+  "gcloud": {
+    "anySubcommand": true,
+    "valueFlags": ["--project", "--account", "--configuration", "--format", "--verbosity",
+      "--impersonate-service-account", "--billing-project", "--flags-file"],
+    "subcommands": {
+      "compute": {
+        "subcommands": {
+          "instances": {"leaves": ["list", "create", "delete", "describe", "start", "stop", "reset",
+            "add-metadata", "set-machine-type", "get-serial-port-output"]},
+          "zones": {"leaves": ["list", "describe"]},
+          "regions": {"leaves": ["list", "describe"]},
+          "disks": {"leaves": ["list", "create", "delete", "describe", "resize", "snapshot"]},
+          "images": {"leaves": ["list", "create", "delete", "describe"]},
+          "firewall-rules": {"leaves": ["list", "create", "delete", "describe", "update"]},
+          "networks": {"leaves": ["list", "create", "delete", "describe"]},
+          "addresses": {"leaves": ["list", "create", "delete", "describe"]}
+        },
+        "leaves": ["ssh", "scp", "config-ssh"]
+      },
+      "auth": {
+        "subcommands": {
+          "application-default": {"leaves": ["login", "print-access-token", "revoke", "set-quota-project"]}
+        },
+        "leaves": ["login", "list", "revoke", "print-access-token", "print-identity-token", "configure-docker",
+          "activate-service-account"]
+      },
+      "config": {
+        "subcommands": {
+          "configurations": {"leaves": ["list", "create", "activate", "delete", "describe", "rename"]}
+        },
+        "leaves": ["set", "get", "get-value", "list", "unset"]
+      },
+      "container": {
+        "subcommands": {
+          "clusters": {"leaves": ["list", "create", "create-auto", "delete", "get-credentials", "describe",
+            "resize", "upgrade", "update"]},
+          "images": {"leaves": ["list", "delete", "describe", "list-tags", "add-tag"]},
+          "node-pools": {"leaves": ["list", "create", "delete", "describe", "update"]}
+        }
+      },
+      "run": {
+        "subcommands": {
+          "services": {"leaves": ["list", "describe", "delete", "update", "update-traffic", "logs"]},
+          "revisions": {"leaves": ["list", "describe", "delete"]},
+          "jobs": {"leaves": ["list", "create", "execute", "delete", "describe"]}
+        },
+        "leaves": ["deploy"]
+      },
+      "projects": {"leaves": ["list", "create", "describe", "delete", "get-iam-policy", "add-iam-policy-binding",
+        "remove-iam-policy-binding"]},
+      "iam": {
+        "subcommands": {
+          "service-accounts": {
+            "subcommands": {"keys": {"leaves": ["create", "list", "delete"]}},
+            "leaves": ["list", "create", "delete", "describe", "add-iam-policy-binding"]
+          },
+          "roles": {"leaves": ["list", "describe", "create", "update", "delete"]}
+        }
+      },
+      "functions": {
+        "subcommands": {"logs": {"leaves": ["read"]}},
+        "leaves": ["deploy", "list", "call", "delete", "describe"]
+      },
+      "app": {
+        "subcommands": {
+          "logs": {"leaves": ["tail", "read"]},
+          "versions": {"leaves": ["list", "delete", "start", "stop"]}
+        },
+        "leaves": ["deploy", "browse", "describe", "create"]
+      },
+      "sql": {
+        "subcommands": {
+          "instances": {"leaves": ["list", "create", "describe", "delete", "patch", "restart"]},
+          "databases": {"leaves": ["list", "create", "delete"]},
+          "users": {"leaves": ["list", "create", "delete", "set-password"]}
+        },
+        "leaves": ["connect"]
+      },
+      "storage": {
+        "subcommands": {"buckets": {"leaves": ["list", "create", "delete", "describe", "update"]}},
+        "leaves": ["ls", "cp", "rm", "mv", "cat", "rsync"]
+      },
+      "builds": {"leaves": ["submit", "list", "log", "describe", "cancel"]},
+      "components": {"leaves": ["list", "install", "update", "remove"]},
+      "logging": {"leaves": ["read", "tail"]},
+      "secrets": {
+        "subcommands": {"versions": {"leaves": ["access", "add", "list", "destroy"]}},
+        "leaves": ["list", "create", "delete", "describe"]
+      },
+      "pubsub": {
+        "subcommands": {
+          "topics": {"leaves": ["list", "create", "delete", "publish"]},
+          "subscriptions": {"leaves": ["list", "create", "delete", "pull"]}
+        }
+      }
+    },
+    "leaves": ["init", "info", "version", "help", "feedback"]
+  },
+  "aws": {
+    "anySubcommand": true,
+    "valueFlags": ["--profile", "--region", "--output", "--endpoint-url", "--query", "--color", "--ca-bundle",
+      "--cli-read-timeout", "--cli-connect-timeout"],
+    "subcommands": {
+      "s3": {"leaves": ["ls", "cp", "mv", "rm", "sync", "mb", "rb", "presign", "website"]},
+      "s3api": {"leaves": ["list-buckets", "list-objects", "list-objects-v2", "get-object", "put-object",
+        "head-object", "create-bucket", "delete-bucket", "get-bucket-policy", "put-bucket-policy"]},
+      "ec2": {"leaves": ["describe-instances", "start-instances", "stop-instances", "terminate-instances",
+        "run-instances", "describe-security-groups", "describe-vpcs", "describe-subnets", "describe-images",
+        "describe-regions", "create-tags"]},
+      "iam": {"leaves": ["list-users", "get-user", "create-user", "list-roles", "get-role", "attach-role-policy",
+        "list-policies", "create-role", "list-access-keys", "create-access-key"]},
+      "sts": {"leaves": ["get-caller-identity", "assume-role", "get-session-token"]},
+      "lambda": {"leaves": ["list-functions", "invoke", "update-function-code", "create-function", "get-function",
+        "delete-function", "update-function-configuration"]},
+      "ecr": {"leaves": ["get-login-password", "get-login", "describe-repositories", "create-repository",
+        "describe-images", "batch-delete-image"]},
+      "ecs": {"leaves": ["list-clusters", "list-services", "describe-services", "update-service", "list-tasks",
+        "describe-tasks", "run-task", "execute-command", "describe-task-definition"]},
+      "eks": {"leaves": ["update-kubeconfig", "list-clusters", "describe-cluster", "list-nodegroups"]},
+      "cloudformation": {"leaves": ["deploy", "describe-stacks", "create-stack", "update-stack", "delete-stack",
+        "list-stacks", "package", "describe-stack-events", "validate-template"]},
+      "logs": {"leaves": ["tail", "describe-log-groups", "get-log-events", "filter-log-events",
+        "describe-log-streams"]},
+      "configure": {"leaves": ["list", "get", "set", "sso", "list-profiles"]},
+      "sso": {"leaves": ["login", "logout"]},
+      "dynamodb": {"leaves": ["list-tables", "scan", "query", "get-item", "put-item", "describe-table",
+        "delete-item", "create-table", "update-item"]},
+      "ssm": {"leaves": ["get-parameter", "get-parameters", "get-parameters-by-path", "put-parameter",
+        "start-session", "send-command"]},
+      "secretsmanager": {"leaves": ["get-secret-value", "list-secrets", "create-secret", "put-secret-value"]},
+      "sqs": {"leaves": ["send-message", "receive-message", "list-queues", "purge-queue", "get-queue-attributes"]},
+      "sns": {"leaves": ["publish", "list-topics", "subscribe"]},
+      "route53": {"leaves": ["list-hosted-zones", "change-resource-record-sets", "list-resource-record-sets"]},
+      "rds": {"leaves": ["describe-db-instances", "describe-db-clusters", "create-db-snapshot"]},
+      "cloudwatch": {"leaves": ["get-metric-statistics", "put-metric-data", "describe-alarms", "get-metric-data"]}
+    },
+    "leaves": ["help"]
+  },
+  "npm": {
+    "anySubcommand": true,
+    "valueFlags": ["--prefix", "--registry", "-w", "--workspace", "--userconfig"],
+    "subcommands": {
+      "audit": {"leaves": ["fix", "signatures"]},
+      "config": {"leaves": ["get", "set", "list", "delete", "edit", "fix"]},
+      "cache": {"leaves": ["clean", "verify", "ls", "add"]}
+    },
+    "leaves": ["install", "i", "ci", "run", "run-script", "test", "t", "start", "stop", "restart", "publish",
+      "init", "create", "update", "up", "uninstall", "rm", "remove", "ls", "list", "outdated", "link", "ln",
+      "pack", "version", "view", "info", "exec", "x", "login", "logout", "whoami", "doctor", "prune", "dedupe",
+      "search", "help", "owner", "dist-tag", "fund", "explain", "rebuild", "root", "prefix", "token",
+      "unpublish", "deprecate", "pkg", "query", "adduser", "access", "org", "profile", "team", "star", "stars",
+      "unstar", "repo", "docs", "bugs", "edit", "shrinkwrap", "sbom", "diff", "hook", "ping", "set-script"]
+  },
+  "yarn": {
+    "anySubcommand": true,
+    "valueFlags": ["--cwd"],
+    "subcommands": {
+      "global": {"leaves": ["add", "remove", "list", "upgrade", "bin", "dir"]},
+      "cache": {"leaves": ["clean", "list", "dir"]},
+      "config": {"leaves": ["get", "set", "list", "delete", "unset"]},
+      "workspaces": {"leaves": ["info", "run", "foreach", "list", "focus"]},
+      "set": {"leaves": ["version", "resolution"]},
+      "plugin": {"leaves": ["import", "list", "remove", "runtime", "check"]},
+      "licenses": {"leaves": ["list", "generate-disclaimer"]},
+      "npm": {"leaves": ["login", "logout", "publish", "info", "audit", "whoami", "tag"]},
+      "constraints": {"leaves": ["query", "source"]}
+    },
+    "leaves": ["add", "install", "remove", "upgrade", "upgrade-interactive", "run", "test", "start", "build",
+      "init", "publish", "info", "list", "why", "outdated", "audit", "link", "unlink", "workspace", "dlx",
+      "version", "create", "pack", "login", "logout", "autoclean", "bin", "check", "dedupe", "exec", "node",
+      "up", "explain", "rebuild", "patch", "patch-commit", "unplug", "stage", "import", "owner", "tag", "team",
+      "policies", "generate-lock-entry", "help", "lint", "dev", "serve", "watch", "format"]
+  },
+  "pnpm": {
+    "valueFlags": ["-C", "--dir", "--filter", "-F"],
+    "leaves": ["add", "install", "i", "remove", "rm", "update", "up", "run", "test", "start", "exec", "dlx",
+      "create", "publish", "list", "ls", "outdated", "why", "audit", "link", "unlink", "store", "prune",
+      "import", "rebuild", "fetch", "init", "pack", "patch", "patch-commit", "setup", "env", "root", "bin",
+      "licenses", "deploy", "dedupe", "server", "config"]
+  },
+  "go": {
+    "anySubcommand": true,
+    "subcommands": {
+      "mod": {"leaves": ["tidy", "download", "init", "vendor", "verify", "why", "graph", "edit"]},
+      "tool": {"leaves": ["pprof", "cover", "trace", "compile", "link", "vet", "nm", "objdump", "asm", "dist",
+        "fix", "cgo", "buildid", "addr2line", "covdata", "test2json"]},
+      "work": {"leaves": ["init", "use", "sync", "edit", "vendor"]}
+    },
+    "leaves": ["build", "run", "test", "get", "install", "fmt", "vet", "generate", "env", "version", "clean",
+      "doc", "list", "bug", "fix", "help", "telemetry"]
+  },
+  "cargo": {
+    "valueFlags": ["-Z", "--config", "--color", "-C", "--manifest-path"],
+    "skipPrefixes": ["+"],
+    "subcommands": {
+      "nextest": {"leaves": ["run", "list", "archive", "show-config"]}
+    },
+    "leaves": ["build", "b", "check", "c", "run", "r", "test", "t", "bench", "clean", "doc", "d", "new", "init",
+      "add", "remove", "rm", "update", "search", "publish", "install", "uninstall", "fmt", "clippy", "fix",
+      "tree", "metadata", "vendor", "package", "login", "logout", "owner", "yank", "help", "version", "rustc",
+      "rustdoc", "generate-lockfile", "locate-project", "pkgid", "verify-project", "report", "audit", "watch",
+      "expand", "llvm-cov", "fetch", "info", "deny", "outdated", "udeps", "machete", "binstall", "make"]
+  },
+  "rustup": {
+    "skipPrefixes": ["+"],
+    "subcommands": {
+      "toolchain": {"leaves": ["install", "list", "uninstall", "link"]},
+      "target": {"leaves": ["add", "list", "remove"]},
+      "component": {"leaves": ["add", "list", "remove"]},
+      "override": {"leaves": ["set", "unset", "list"]},
+      "show": {"leaves": ["active-toolchain", "home", "profile"]},
+      "self": {"leaves": ["update", "uninstall", "upgrade-data"]},
+      "set": {"leaves": ["profile", "default-host", "auto-self-update"]}
+    },
+    "leaves": ["update", "default", "run", "which", "doc", "install", "uninstall", "check", "completions",
+      "help", "man"]
+  },
+  "terraform": {
+    "subcommands": {
+      "state": {"leaves": ["list", "show", "mv", "rm", "pull", "push", "replace-provider"]},
+      "workspace": {"leaves": ["list", "new", "select", "delete", "show"]},
+      "providers": {"leaves": ["lock", "mirror", "schema"]}
+    },
+    "leaves": ["init", "plan", "apply", "destroy", "validate", "fmt", "output", "show", "import", "console",
+      "graph", "refresh", "taint", "untaint", "login", "logout", "force-unlock", "get", "test", "version",
+      "metadata", "modules"]
+  },
+  "gh": {
+    "valueFlags": ["-R", "--repo"],
+    "subcommands": {
+      "pr": {"leaves": ["create", "list", "view", "checkout", "co", "merge", "close", "reopen", "status",
+        "review", "diff", "checks", "edit", "ready", "comment", "lock", "unlock"]},
+      "issue": {"leaves": ["create", "list", "view", "close", "reopen", "status", "comment", "edit", "delete",
+        "transfer", "pin", "unpin", "develop", "lock", "unlock"]},
+      "repo": {"leaves": ["clone", "create", "view", "fork", "list", "sync", "delete", "rename", "edit",
+        "archive", "unarchive", "set-default", "deploy-key", "gitignore", "license"]},
+      "run": {"leaves": ["list", "view", "watch", "rerun", "cancel", "download", "delete"]},
+      "workflow": {"leaves": ["list", "view", "run", "enable", "disable"]},
+      "auth": {"leaves": ["login", "logout", "status", "refresh", "token", "setup-git", "switch"]},
+      "release": {"leaves": ["create", "list", "view", "download", "upload", "delete", "edit", "delete-asset"]},
+      "gist": {"leaves": ["create", "list", "view", "edit", "delete", "clone", "rename"]},
+      "secret": {"leaves": ["set", "list", "delete", "remove"]},
+      "variable": {"leaves": ["set", "list", "delete", "get"]},
+      "codespace": {"leaves": ["create", "list", "ssh", "code", "delete", "stop", "logs", "ports", "cp", "view",
+        "edit", "rebuild", "jupyter"]},
+      "alias": {"leaves": ["set", "list", "delete", "import"]},
+      "extension": {"leaves": ["install", "list", "remove", "upgrade", "browse", "create", "exec", "search"]},
+      "config": {"leaves": ["get", "set", "list", "clear-cache"]},
+      "search": {"leaves": ["repos", "issues", "prs", "code", "commits"]},
+      "label": {"leaves": ["create", "list", "edit", "delete", "clone"]},
+      "cache": {"leaves": ["list", "delete"]},
+      "ssh-key": {"leaves": ["add", "list", "delete"]},
+      "gpg-key": {"leaves": ["add", "list", "delete"]},
+      "project": {"leaves": ["list", "view", "create", "edit", "close", "delete", "item-list", "item-add"]}
+    },
+    "leaves": ["api", "browse", "status", "completion", "help", "version"]
+  },
+  "pip": {
+    "valueFlags": ["--python", "--log", "--proxy", "--cache-dir"],
+    "subcommands": {
+      "config": {"leaves": ["list", "edit", "get", "set", "unset", "debug"]},
+      "cache": {"leaves": ["dir", "info", "list", "remove", "purge"]},
+      "index": {"leaves": ["versions"]}
+    },
+    "leaves": ["install", "uninstall", "freeze", "list", "show", "download", "wheel", "check", "search", "hash",
+      "completion", "debug", "help", "inspect", "lock"]
+  },
+  "pip3": {
+    "valueFlags": ["--python", "--log", "--proxy", "--cache-dir"],
+    "subcommands": {
+      "config": {"leaves": ["list", "edit", "get", "set", "unset", "debug"]},
+      "cache": {"leaves": ["dir", "info", "list", "remove", "purge"]},
+      "index": {"leaves": ["versions"]}
+    },
+    "leaves": ["install", "uninstall", "freeze", "list", "show", "download", "wheel", "check", "search", "hash",
+      "completion", "debug", "help", "inspect", "lock"]
+  },
+  "apt": {
+    "anySubcommand": true,
+    "valueFlags": ["-o", "-c", "-t"],
+    "leaves": ["install", "remove", "purge", "update", "upgrade", "full-upgrade", "dist-upgrade", "autoremove",
+      "search", "show", "list", "policy", "source", "build-dep", "download", "clean", "autoclean",
+      "edit-sources", "reinstall", "satisfy", "changelog", "depends", "rdepends"]
+  },
+  "apt-get": {
+    "anySubcommand": true,
+    "valueFlags": ["-o", "-c", "-t"],
+    "leaves": ["install", "remove", "purge", "update", "upgrade", "dist-upgrade", "autoremove", "source",
+      "build-dep", "download", "clean", "autoclean", "check", "changelog", "satisfy", "dselect-upgrade"]
+  },
+  "brew": {
+    "anySubcommand": true,
+    "subcommands": {
+      "services": {"leaves": ["list", "start", "stop", "restart", "run", "cleanup", "info", "kill"]},
+      "bundle": {"leaves": ["install", "dump", "cleanup", "check", "exec", "list", "edit", "env", "sh"]},
+      "cask": {"leaves": ["install", "uninstall", "list", "upgrade", "info", "outdated"]}
+    },
+    "leaves": ["install", "uninstall", "remove", "rm", "upgrade", "update", "list", "ls", "search", "info",
+      "doctor", "cleanup", "tap", "untap", "outdated", "link", "unlink", "pin", "unpin", "reinstall", "deps",
+      "uses", "leaves", "config", "home", "edit", "create", "audit", "autoremove", "shellenv", "commands",
+      "log", "fetch", "analytics", "desc", "missing", "migrate", "options", "tap-info", "developer", "help"]
+  },
+  "systemctl": {
+    "anySubcommand": true,
+    "valueFlags": ["-H", "--host", "-M", "--machine", "-t", "--type", "-p", "--property", "--state"],
+    "leaves": ["start", "stop", "restart", "reload", "status", "enable", "disable", "is-active", "is-enabled",
+      "is-failed", "list-units", "list-unit-files", "list-timers", "list-sockets", "list-dependencies",
+      "daemon-reload", "daemon-reexec", "mask", "unmask", "cat", "edit", "show", "kill", "reset-failed",
+      "isolate", "reboot", "poweroff", "suspend", "hibernate", "set-default", "get-default", "try-restart",
+      "reload-or-restart", "show-environment", "set-environment", "unset-environment", "link", "revert",
+      "preset", "list-jobs", "halt", "emergency", "rescue"]
+  }
+}`
@@ -0,0 +1,121 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestFindSubcommandPath(t *testing.T) {
+	path, rest := findSubcommandPath([]string{"gcloud", "compute", "instances", "list", "--filter", "x"})
+	assert.Equal(t, []string{"compute", "instances", "list"}, path)
+	assert.Equal(t, []string{"--filter", "x"}, rest)
+
+	path, rest = findSubcommandPath([]string{"git", "-C", "myrepo", "remote", "add", "origin", "url"})
+	assert.Equal(t, []string{"remote", "add"}, path)
+	assert.Equal(t, []string{"-C", "myrepo", "origin", "url"}, rest)
+
+	path, rest = findSubcommandPath([]string{"kubectl", "get", "-n", "prod", "pods", "-o", "wide"})
+	assert.Equal(t, []string{"get", "pods"}, path)
+	assert.Equal(t, []string{"-n", "prod", "-o", "wide"}, rest)
+
+	path, _ = findSubcommandPath([]string{"docker", "compose", "-f", "dev.yml", "up", "-d"})
+	assert.Equal(t, []string{"compose", "up"}, path)
+
+	path, rest = findSubcommandPath([]string{"cargo", "+nightly", "build", "--release"})
+	assert.Equal(t, []string{"build"}, path)
+	assert.Equal(t, []string{"--release"}, rest)
+
+	// Positional arguments aren't subcommands unless the level accepts any word
+	path, rest = findSubcommandPath([]string{"docker", "myimage"})
+	assert.Equal(t, []string{}, path)
+	assert.Equal(t, []string{"myimage"}, rest)
+
+	// Words we don't know never leak as subcommands
+	path, rest = findSubcommandPath([]string{"git", "my-secret-branch-name", "status"})
+	assert.Equal(t, []string{UnknownSubcommand}, path)
+	assert.Equal(t, []string{"status"}, rest)
+
+	path, _ = findSubcommandPath([]string{"go", "./cmd/mytool"})
+	assert.Equal(t, []string{UnknownSubcommand}, path)
+
+	path, rest = findSubcommandPath([]string{"ls", "-la"})
+	assert.Equal(t, []string{}, path)
+	assert.Equal(t, []string{"-la"}, rest)
+}
+
+func TestRedactCommandSubcommandPath(t *testing.T) {
+	r := RedactCommand(shell.Bash, []string{"gcloud --project my-project compute instances list"})
+	assert.Equal(t, "compute", r.Subcommand)
+	assert.Equal(t, []string{"compute", "instances", "list"}, r.SubcommandPath)
+	assert.Equal(t, []string{"project"}, r.Options)
+	assert.Equal(t, "gcloud compute instances list [flags: project]", r.Preview())
+
+	r = RedactCommand(shell.Bash, []string{"git my-secret-branch-name"})
+	assert.Equal(t, UnknownSubcommand, r.Subcommand)
+	assert.True(t, r.HasUnknownSubcommand())
+	assert.NotContains(t, r.Preview(), "my-secret-branch-name")
+
+	r = RedactCommand(shell.Bash, []string{"ls -la"})
+	assert.Equal(t, "", r.Subcommand)
+	assert.Equal(t, []string{}, r.SubcommandPath)
+	assert.False(t, r.HasUnknownSubcommand())
+}
+
+func TestUnknownSubcommands(t *testing.T) {
+	history := &ShellHistory{RedactedLines: []*RedactedCommand{
+		RedactCommand(shell.Bash, []string{"git status"}),
+		RedactCommand(shell.Bash, []string{"git fixup-alias"}),
+		RedactCommand(shell.Bash, []string{"yarn my-script"}),
+	}}
+	assert.Equal(t, 2, history.UnknownSubcommands())
+}
+
+func TestLoadSubcommandRegistry(t *testing.T) {
+	registryFile, err := ioutil.TempFile("", "subcommands*.json")
+	assert.Nil(t, err)
+	defer os.Remove(registryFile.Name())
+	_, err = registryFile.WriteString(`{
+  "acmectl": {"valueFlags": ["--env"], "subcommands": {"deploy": {"leaves": ["start", "rollback"]}}},
+  "git": {"subcommands": {"flow": {"subcommands": {"feature": {"leaves": ["start", "finish"]}}}}}
+}`)
+	assert.Nil(t, err)
+	registryFile.Close()
+
+	assert.Nil(t, LoadSubcommandRegistry(registryFile.Name()))
+
+	path, _ := findSubcommandPath([]string{"acmectl", "--env", "staging", "deploy", "rollback"})
+	assert.Equal(t, []string{"deploy", "rollback"}, path)
+
+	// Merged into the built in git tree, which still has its own subcommands
+	path, _ = findSubcommandPath([]string{"git", "flow", "feature", "start", "my-feature"})
+	assert.Equal(t, []string{"flow", "feature", "start"}, path)
+	path, _ = findSubcommandPath([]string{"git", "stash", "pop"})
+	assert.Equal(t, []string{"stash", "pop"}, path)
+
+	assert.NotNil(t, LoadSubcommandRegistry(registryFile.Name()+".missing"))
+}
+
+func TestLoadSubcommandRegistryNullTree(t *testing.T) {
+	for registry, key := range map[string]string{
+		`{"foo": null}`: "foo",
+		`{"git": null}`: "git",
+		`{"foo": {"subcommands": {"bar": {"subcommands": {"baz": null}}}}}`: "foo bar baz",
+	} {
+		registryFile, err := ioutil.TempFile("", "subcommands*.json")
+		assert.Nil(t, err)
+		defer os.Remove(registryFile.Name())
+		registryFile.WriteString(registry)
+		registryFile.Close()
+
+		err = LoadSubcommandRegistry(registryFile.Name())
+		if assert.NotNil(t, err, registry) {
+			assert.Contains(t, err.Error(), "for "+key+" must be")
+		}
+	}
+	_, ok := subcommandRegistry["foo"]
+	assert.False(t, ok)
+}
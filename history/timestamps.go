@@ -0,0 +1,77 @@
+package history
+
+import (
+	"math"
+	"time"
+)
+
+// TimestampPrecision is how precisely the times commands ran at are shared
+type TimestampPrecision string
+
+const (
+	// ExactTimestamps keeps timestamps to the second
+	ExactTimestamps TimestampPrecision = "Exact"
+
+	// HourTimestamps rounds timestamps down to the hour
+	HourTimestamps = "Hour"
+
+	// DayTimestamps rounds timestamps down to the day
+	DayTimestamps = "Day"
+
+	// RelativeTimestamps replaces timestamps with the time since the first command
+	RelativeTimestamps = "Relative"
+)
+
+// CoarsenTimestamp rounds a timestamp down to the given precision and returns it in
+// UTC along with the offset from UTC of the timezone it was recorded in, rounded to
+// whole hours. Local time of day is then the UTC time plus the offset. Days are
+// rounded in a zone with the rounded offset, and hours in UTC, so a coarse timestamp
+// is always on the hour and doesn't give away a zone like +05:30. Zero timestamps, and
+// relative precision, return a zero time and offset.
+func CoarsenTimestamp(t time.Time, precision TimestampPrecision) (time.Time, int) {
+	if t.IsZero() || precision == RelativeTimestamps {
+		return time.Time{}, 0
+	}
+	_, offsetSecs := t.Zone()
+	offset := int(math.Round(float64(offsetSecs) / float64(time.Hour/time.Second)))
+	switch precision {
+	case HourTimestamps:
+		t = t.UTC().Truncate(time.Hour)
+	case DayTimestamps:
+		local := t.In(time.FixedZone("", offset*int(time.Hour/time.Second)))
+		year, month, day := local.Date()
+		t = time.Date(year, month, day, 0, 0, 0, 0, local.Location())
+	default:
+		t = t.Truncate(time.Second)
+	}
+	return t.UTC(), offset
+}
+
+// RelativeTimestamp returns how long after first a command ran, to the second, or zero
+// if either time is unknown
+func RelativeTimestamp(t time.Time, first time.Time) time.Duration {
+	if t.IsZero() || first.IsZero() {
+		return 0
+	}
+	return t.Sub(first).Truncate(time.Second)
+}
+
+// FirstTimestamp returns the earliest command timestamp in the history, or a zero time
+// if none of the commands have one
+func (h *ShellHistory) FirstTimestamp() time.Time {
+	var first time.Time
+	for _, r := range h.RedactedLines {
+		if r != nil {
+			first = earlierTimestamp(first, r.Timestamp)
+		}
+	}
+	return first
+}
+
+// earlierTimestamp returns the earlier of two times, ignoring either if it is zero
+func earlierTimestamp(first time.Time, timestamp time.Time) time.Time {
+	if timestamp.IsZero() || (!first.IsZero() && !timestamp.Before(first)) {
+		return first
+	}
+	return timestamp
+}
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestCoarsenTimestamp(t *testing.T) {
+	pacific := time.FixedZone("PDT", -7*60*60)
+	ran := time.Date(2020, 5, 19, 21, 42, 17, 500, pacific)
+
+	exact, offset := CoarsenTimestamp(ran, ExactTimestamps)
+	assert.Equal(t, time.Date(2020, 5, 20, 4, 42, 17, 0, time.UTC), exact)
+	assert.Equal(t, -7, offset)
+
+	hour, _ := CoarsenTimestamp(ran, HourTimestamps)
+	assert.Equal(t, time.Date(2020, 5, 20, 4, 0, 0, 0, time.UTC), hour)
+
+	// The day is the one the command ran on locally, not in UTC
+	day, offset := CoarsenTimestamp(ran, DayTimestamps)
+	assert.Equal(t, time.Date(2020, 5, 19, 7, 0, 0, 0, time.UTC), day)
+	assert.Equal(t, 19, day.Add(time.Duration(offset)*time.Hour).Day())
+
+	relative, offset := CoarsenTimestamp(ran, RelativeTimestamps)
+	assert.True(t, relative.IsZero())
+	assert.Equal(t, 0, offset)
+
+	unknown, offset := CoarsenTimestamp(time.Time{}, HourTimestamps)
+	assert.True(t, unknown.IsZero())
+	assert.Equal(t, 0, offset)
+}
+
+func TestCoarsenTimestampRoundsOffset(t *testing.T) {
+	india := time.FixedZone("IST", 5*60*60+30*60)
+	ran := time.Date(2020, 5, 19, 9, 15, 0, 0, india)
+
+	hour, offset := CoarsenTimestamp(ran, HourTimestamps)
+	assert.Equal(t, 6, offset)
+	// 03:45 UTC, so 03:00 and not 03:30, the local hour in UTC
+	assert.Equal(t, time.Date(2020, 5, 19, 3, 0, 0, 0, time.UTC), hour)
+
+	day, offset := CoarsenTimestamp(ran, DayTimestamps)
+	assert.Equal(t, time.Date(2020, 5, 18, 18, 0, 0, 0, time.UTC), day)
+	assert.Equal(t, 19, day.Add(time.Duration(offset)*time.Hour).Day())
+
+	nepal := time.FixedZone("NPT", 5*60*60+45*60)
+	hour, offset = CoarsenTimestamp(time.Date(2020, 5, 19, 9, 15, 0, 0, nepal), HourTimestamps)
+	assert.Equal(t, 6, offset)
+	assert.Equal(t, time.Date(2020, 5, 19, 3, 0, 0, 0, time.UTC), hour)
+}
+
+func TestRelativeTimestamp(t *testing.T) {
+	h := ShellHistory{RedactedLines: []*RedactedCommand{
+		RedactCommand(shell.Zsh, []string{": 1584112400:0;ls"}),
+		RedactCommand(shell.Zsh, []string{"pwd"}),
+		RedactCommand(shell.Zsh, []string{": 1584112360:0;cd"}),
+	}}
+	first := h.FirstTimestamp()
+	assert.Equal(t, time.Unix(1584112360, 0), first)
+	assert.Equal(t, 40*time.Second, RelativeTimestamp(h.RedactedLines[0].Timestamp, first))
+	assert.Equal(t, time.Duration(0), RelativeTimestamp(h.RedactedLines[1].Timestamp, first))
+}
@@ -0,0 +1,139 @@
+package history
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// commandWrapper describes a command like sudo or xargs that runs another command
+// passed to it as arguments
+type commandWrapper struct {
+	// valueFlags are the wrapper's options that take a value, which we have to skip
+	// over to find the wrapped command
+	valueFlags []string
+
+	// positionalArgs is the number of arguments between the wrapper's options and
+	// the wrapped command, e.g. 1 for the duration in timeout 10s make
+	positionalArgs int
+
+	// acceptsAssignments is true if NAME=value arguments before the wrapped command
+	// set environment variables for it, as with env and sudo
+	acceptsAssignments bool
+}
+
+// Wrapper commands we look through to find the command that actually ran
+var commandWrappers = map[string]commandWrapper{
+	"sudo": {
+		valueFlags: []string{"-u", "-g", "-h", "-p", "-C", "-D", "-r", "-t", "-U", "-T",
+			"--user", "--group", "--host", "--prompt", "--close-from", "--chdir", "--role", "--type",
+			"--other-user", "--command-timeout"},
+		acceptsAssignments: true},
+	"doas": {valueFlags: []string{"-u", "-C"}},
+	"env": {
+		valueFlags:         []string{"-u", "-C", "-S", "--unset", "--chdir", "--split-string"},
+		acceptsAssignments: true},
+	"time":  {valueFlags: []string{"-f", "-o", "--format", "--output"}},
+	"nohup": {},
+	"xargs": {
+		valueFlags: []string{"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s",
+			"--arg-file", "--delimiter", "--max-lines", "--max-args", "--max-procs", "--max-chars",
+			"--process-slot-var"}},
+	"watch":      {valueFlags: []string{"-n", "--interval"}},
+	"nice":       {valueFlags: []string{"-n", "--adjustment"}},
+	"ionice":     {valueFlags: []string{"-c", "-n", "-p", "--class", "--classdata", "--pid"}},
+	"timeout":    {valueFlags: []string{"-s", "-k", "--signal", "--kill-after"}, positionalArgs: 1},
+	"stdbuf":     {valueFlags: []string{"-i", "-o", "-e", "--input", "--output", "--error"}},
+	"caffeinate": {valueFlags: []string{"-t", "-w"}},
+	"exec":       {valueFlags: []string{"-a"}},
+	"command":    {},
+	"builtin":    {},
+	"chronic":    {},
+	"unbuffer":   {},
+}
+
+var assignmentRegEx = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=`)
+
+// unwrapCommand strips leading environment variable assignments and wrapper commands
+// from the words of a simple command, returning the words of the command that actually
+// runs, the wrappers around it outermost first, and the names of the variables that
+// were set. Assignment values and the wrappers' own arguments are dropped.
+func unwrapCommand(words []string) (effective []string, wrappers []string, envVars []string) {
+	wrappers = make([]string, 0)
+	envVars = make([]string, 0)
+
+	words, envVars = stripAssignments(words, envVars)
+	for len(words) > 1 {
+		wrapper, ok := commandWrappers[words[0]]
+		if !ok {
+			break
+		}
+		rest := skipWrapperArgs(wrapper, words[1:])
+		if wrapper.acceptsAssignments {
+			rest, envVars = stripAssignments(rest, envVars)
+		}
+		if len(rest) == 0 {
+			// Nothing wrapped, e.g. sudo -i, so the wrapper is the command
+			break
+		}
+		if len(rest) == 1 && strings.ContainsAny(rest[0], " \t") {
+			// watch and friends are often passed the whole command as one argument
+			if split, err := shellquote.Split(rest[0]); err == nil && len(split) > 0 {
+				rest = split
+			}
+		}
+		wrappers = append(wrappers, words[0])
+		words = rest
+		words, envVars = stripAssignments(words, envVars)
+	}
+	return words, wrappers, envVars
+}
+
+// stripAssignments removes leading NAME=value words, appending the names to envVars
+func stripAssignments(words []string, envVars []string) ([]string, []string) {
+	for len(words) > 0 {
+		match := assignmentRegEx.FindStringSubmatch(words[0])
+		if match == nil {
+			break
+		}
+		envVars = append(envVars, match[1])
+		words = words[1:]
+	}
+	return words, envVars
+}
+
+// skipWrapperArgs skips over a wrapper's own options and positional arguments and
+// returns the remaining words
+func skipWrapperArgs(wrapper commandWrapper, args []string) []string {
+	i := 0
+	for i < len(args) && len(args[i]) > 1 && strings.HasPrefix(args[i], "-") {
+		arg := args[i]
+		i++
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "--") {
+			if !strings.Contains(arg, "=") && wrapper.takesValue(arg) {
+				i++
+			}
+		} else if len(arg) == 2 && wrapper.takesValue(arg) {
+			// -n 10 takes the next word, a glued value like -n10 doesn't
+			i++
+		}
+	}
+	i += wrapper.positionalArgs
+	if i > len(args) {
+		return []string{}
+	}
+	return args[i:]
+}
+
+func (w commandWrapper) takesValue(flag string) bool {
+	for _, valueFlag := range w.valueFlags {
+		if valueFlag == flag {
+			return true
+		}
+	}
+	return false
+}
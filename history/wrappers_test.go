@@ -0,0 +1,55 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestUnwrapCommand(t *testing.T) {
+	effective, wrappers, envVars := unwrapCommand([]string{"sudo", "-u", "deploy", "-E", "apt", "install", "foo"})
+	assert.Equal(t, []string{"apt", "install", "foo"}, effective)
+	assert.Equal(t, []string{"sudo"}, wrappers)
+	assert.Equal(t, []string{}, envVars)
+
+	effective, wrappers, envVars = unwrapCommand([]string{"FOO=bar", "BAZ=", "env", "-u", "HOME", "X=1", "nohup", "make", "-j4"})
+	assert.Equal(t, []string{"make", "-j4"}, effective)
+	assert.Equal(t, []string{"env", "nohup"}, wrappers)
+	assert.Equal(t, []string{"FOO", "BAZ", "X"}, envVars)
+
+	effective, wrappers, _ = unwrapCommand([]string{"timeout", "-s", "KILL", "10s", "xargs", "-I{}", "-n", "1", "grep", "-l", "{}"})
+	assert.Equal(t, []string{"grep", "-l", "{}"}, effective)
+	assert.Equal(t, []string{"timeout", "xargs"}, wrappers)
+
+	effective, wrappers, _ = unwrapCommand([]string{"watch", "-n", "2", "kubectl get pods"})
+	assert.Equal(t, []string{"kubectl", "get", "pods"}, effective)
+	assert.Equal(t, []string{"watch"}, wrappers)
+
+	effective, wrappers, _ = unwrapCommand([]string{"sudo", "-i"})
+	assert.Equal(t, []string{"sudo", "-i"}, effective)
+	assert.Equal(t, []string{}, wrappers)
+}
+
+func TestRedactCommandWrappers(t *testing.T) {
+	r := RedactCommand(shell.Bash, []string{"sudo apt install foo"})
+	assert.Equal(t, "apt", r.Command)
+	assert.Equal(t, "install", r.Subcommand)
+	assert.Equal(t, []string{"sudo"}, r.Wrappers)
+	assert.Equal(t, 4, r.NumTokens)
+
+	r = RedactCommand(shell.Bash, []string{"GITHUB_TOKEN=ghp_secret make release"})
+	assert.Equal(t, "make", r.Command)
+	assert.Equal(t, []string{"GITHUB_TOKEN"}, r.EnvVars)
+	assert.NotContains(t, r.Preview(), "ghp_secret")
+
+	r = RedactCommand(shell.Bash, []string{"time go test ./..."})
+	assert.Equal(t, "go", r.Command)
+	assert.Equal(t, "test", r.Subcommand)
+	assert.Equal(t, []string{"time"}, r.Wrappers)
+
+	rs := RedactCommands(shell.Bash, []string{"find . -name '*.go' | xargs -n 1 gofmt -l"})
+	assert.Equal(t, "gofmt", rs[1].Command)
+	assert.Equal(t, []string{"xargs"}, rs[1].Wrappers)
+	assert.Equal(t, []string{"l"}, rs[1].Options)
+}
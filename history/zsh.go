@@ -0,0 +1,95 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZshHistoryLineRegEx parses a single entry of a zsh history file.
+// Zsh format with EXTENDED_HISTORY set
+// : <timestamp>:<elapsed seconds>;<command>
+//
+// Without EXTENDED_HISTORY each entry is just
+// <command>
+//
+// Newlines inside a command are written as a backslash followed by a newline, so
+// an entry can span several lines of the file.
+var ZshHistoryLineRegEx = regexp.MustCompile(`(?s)^: (\d+):(\d+);(.*)$`)
+
+// zshMeta is the byte zsh uses to escape bytes that have special meaning inside
+// the shell. The escaped byte follows it, xor'd with 32.
+const zshMeta = 0x83
+
+// readZshHistory splits a zsh history file into entries, joining continuation
+// lines and decoding zsh's metafied bytes. Every line belongs to an entry, so the
+// unattributed line count is always zero.
+func readZshHistory(reader *bufio.Reader, emit func(entry []string)) (int, error) {
+	var entry strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasSuffix(line, "\\") && err != io.EOF {
+			// Same rule zsh uses when reading the file back: a trailing backslash
+			// stands for a newline in the command.
+			entry.WriteString(line[:len(line)-1])
+			entry.WriteByte('\n')
+			continue
+		}
+		entry.WriteString(line)
+		if command := strings.TrimSpace(entry.String()); len(command) > 0 {
+			emit([]string{unmetafyZsh(command)})
+		}
+		entry.Reset()
+		if err == io.EOF {
+			break
+		}
+	}
+	return 0, nil
+}
+
+// parseZshLine returns the timestamp, elapsed time and command of a zsh history entry
+func parseZshLine(line string) (commandTime time.Time, duration time.Duration, command string) {
+	// Split off the timestamp
+	res := ZshHistoryLineRegEx.FindStringSubmatch(line)
+	if len(res) < 4 {
+		// Assume this is the non-timestamped zsh format
+		command = line
+		return
+	}
+	timestampSecs, err := strconv.Atoi(res[1])
+	if err != nil {
+		return
+	}
+	commandTime = time.Unix(int64(timestampSecs), 0)
+	elapsedSecs, err := strconv.Atoi(res[2])
+	if err == nil {
+		duration = time.Duration(elapsedSecs) * time.Second
+	}
+	command = res[3]
+	return
+}
+
+// unmetafyZsh decodes the metafied encoding zsh uses when writing non-ASCII
+// commands to its history file.
+func unmetafyZsh(metafied string) string {
+	if strings.IndexByte(metafied, zshMeta) < 0 {
+		return metafied
+	}
+	b := make([]byte, 0, len(metafied))
+	for i := 0; i < len(metafied); i++ {
+		if metafied[i] == zshMeta && i+1 < len(metafied) {
+			i++
+			b = append(b, metafied[i]^32)
+		} else {
+			b = append(b, metafied[i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,44 @@
+package history
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestRedactCommandZshDuration(t *testing.T) {
+	r := RedactCommand(shell.Zsh, []string{": 1584112360:42;make -j4"})
+	assert.Equal(t, "make", r.Command)
+	assert.Equal(t, time.Unix(1584112360, 0), r.Timestamp)
+	assert.Equal(t, 42*time.Second, r.Duration)
+}
+
+func TestReadZshHistoryContinuations(t *testing.T) {
+	file := ": 1584112360:0;ls\n" +
+		": 1584112361:3;make \\\\\n" +
+		"  -j4\n" +
+		": 1584112362:0;f() {\\\n" +
+		"  echo hi\\\n" +
+		"}\n"
+	entries, _, err := readAllEntries(readZshHistory, bufio.NewReader(strings.NewReader(file)))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, ": 1584112361:3;make \\\n  -j4", entries[1][0])
+	assert.Equal(t, ": 1584112362:0;f() {\n  echo hi\n}", entries[2][0])
+
+	r := RedactCommand(shell.Zsh, entries[1])
+	assert.Equal(t, "make", r.Command)
+	assert.Equal(t, "jobs", r.Options[0])
+	assert.Equal(t, 3*time.Second, r.Duration)
+}
+
+func TestUnmetafyZsh(t *testing.T) {
+	// Bytes outside 0x83-0xa2 are written as is
+	assert.Equal(t, "echo é", unmetafyZsh("echo é"))
+	// "’" is 0xe2 0x80 0x99, and 0x99 is metafied as 0x83 0xb9
+	assert.Equal(t, "echo ’", unmetafyZsh("echo \xe2\x80\x83\xb9"))
+}
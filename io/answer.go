@@ -1,7 +1,11 @@
 package io
 
 import (
+	"log"
+	"time"
+
 	"github.com/warpdotdev/warp-cli-survey/history"
+	"github.com/warpdotdev/warp-cli-survey/shell"
 	"github.com/warpdotdev/warp-cli-survey/store"
 )
 
@@ -47,16 +51,67 @@ type Answer struct {
 
 	// History is the redacted history model for File type questions
 	History *history.ShellHistory
+
+	// OpenHistoryStream is set instead of History for a history too long to hold in
+	// memory. It reopens the history so it can be redacted and uploaded a batch at a
+	// time.
+	OpenHistoryStream func() (*history.HistoryStream, error)
+
+	// OmitHashes is true if the user asked not to upload the hashes of
+	// their commands
+	OmitHashes bool
+
+	// HistoryDetail is how much of History the user chose to upload
+	HistoryDetail HistoryDetail
+
+	// TimestampPrecision is how precisely the user chose to share when they ran
+	// each command. Empty means exact.
+	TimestampPrecision history.TimestampPrecision
 }
 
 // Response returns a response model suitable for storing or sending to a server
 func (r *Answer) Response(respondentID string, questionNum int) store.Response {
 	return store.Response{
-		RespondentID: respondentID,
-		QuestionNum:  questionNum,
-		QuestionID:   string(r.Question.ID),
-		Answers:      r.getAnswers(respondentID, questionNum),
-		HistoryLines: r.getHistoryLines(respondentID),
+		RespondentID:  respondentID,
+		QuestionNum:   questionNum,
+		QuestionID:    string(r.Question.ID),
+		Answers:       r.getAnswers(respondentID, questionNum),
+		HistoryLines:  r.getHistoryLines(respondentID),
+		CommandCounts: r.getCommandCounts(respondentID),
+	}
+}
+
+// Write stores the response to the question. History lines are sent in batches of at
+// most store.HistoryBatchSize, so a long history is never copied or encoded all at once,
+// and one read from OpenHistoryStream is never held in memory.
+func (r *Answer) Write(storage store.Storer, respondentID string, questionNum int) {
+	response := store.Response{
+		RespondentID:  respondentID,
+		QuestionNum:   questionNum,
+		QuestionID:    string(r.Question.ID),
+		Answers:       r.getAnswers(respondentID, questionNum),
+		HistoryLines:  make([]store.HistoryLine, 0),
+		CommandCounts: r.getCommandCounts(respondentID),
+	}
+	written := false
+	r.forEachHistoryLine(respondentID, func(line store.HistoryLine) {
+		response.HistoryLines = append(response.HistoryLines, line)
+		if len(response.HistoryLines) < store.HistoryBatchSize {
+			return
+		}
+		storage.Write(response)
+		written = true
+		response = store.Response{
+			RespondentID:  respondentID,
+			QuestionNum:   questionNum,
+			QuestionID:    string(r.Question.ID),
+			Answers:       make([]store.Answer, 0),
+			HistoryLines:  make([]store.HistoryLine, 0, store.HistoryBatchSize),
+			CommandCounts: make([]store.CommandCount, 0),
+		}
+	})
+	if !written || len(response.HistoryLines) > 0 {
+		storage.Write(response)
 	}
 }
 
@@ -96,30 +151,184 @@ func (r *Answer) getAnswers(respondentID string, questionNum int) []store.Answer
 	return []store.Answer{}
 }
 
+// getHistoryLines returns a line for each command in the history, with as much detail
+// as the user chose to upload
 func (r Answer) getHistoryLines(respondentID string) []store.HistoryLine {
 	historyRecords := make([]store.HistoryLine, 0)
-	history := r.History
-	if history == nil {
-		return historyRecords
+	r.forEachHistoryLine(respondentID, func(line store.HistoryLine) {
+		historyRecords = append(historyRecords, line)
+	})
+	return historyRecords
+}
+
+// forEachHistoryLine calls fn with a line for each command in the history, one at a
+// time, with as much detail as the user chose to upload
+func (r Answer) forEachHistoryLine(respondentID string, fn func(line store.HistoryLine)) {
+	if r.HistoryDetail == CountsOnly {
+		return
+	}
+	if r.History == nil {
+		r.forEachStreamedHistoryLine(respondentID, fn)
+		return
 	}
 
+	history := r.History
+	firstTimestamp := history.FirstTimestamp()
 	for i, record := range history.RedactedLines {
-		if record != nil {
-			historyRecords = append(historyRecords, store.HistoryLine{
-				RespondentID:     respondentID,
-				QuestionID:       string(r.Question.ID),
-				FileName:         history.FileName,
-				ShellType:        history.ShellType,
-				LineNum:          i,
-				Command:          record.Command,
-				Subcommand:       record.Subcommand,
-				Options:          record.Options,
-				Sha1:             record.Sha1,
-				Length:           record.Length,
-				CommandTimestamp: record.Timestamp,
-			})
+		if record == nil {
+			continue
 		}
+		fileName, shellType := history.CommandSource(record)
+		fn(r.historyLine(respondentID, i, record, fileName, shellType, firstTimestamp))
 	}
+}
 
-	return historyRecords
+// forEachStreamedHistoryLine calls fn with a line for each command in the history
+// stream, redacting the history as it goes rather than holding it all in memory
+func (r Answer) forEachStreamedHistoryLine(respondentID string, fn func(line store.HistoryLine)) {
+	if r.OpenHistoryStream == nil {
+		return
+	}
+	var firstTimestamp time.Time
+	detailed := r.HistoryDetail != CommandsOnly && r.HistoryDetail != CommandsAndSubcommands
+	if detailed && r.timestampPrecision() == history.RelativeTimestamps {
+		// Relative times need the first command's time before anything is sent
+		stream, err := r.OpenHistoryStream()
+		if err != nil {
+			log.Println("Error reading history file", err)
+			return
+		}
+		if firstTimestamp, err = stream.FirstTimestamp(); err != nil {
+			log.Println("Error reading history file", err)
+			return
+		}
+	}
+
+	stream, err := r.OpenHistoryStream()
+	if err != nil {
+		log.Println("Error reading history file", err)
+		return
+	}
+	i := 0
+	for redaction := range stream.Redactions {
+		for _, record := range redaction.Commands {
+			fn(r.historyLine(respondentID, i, record, stream.FileName, stream.ShellType, firstTimestamp))
+			i++
+		}
+	}
+	if _, err := stream.Wait(); err != nil {
+		log.Println("Error reading history file", err)
+	}
+}
+
+// historyLine returns the line uploaded for a command, with as much detail as the user
+// chose to upload
+func (r Answer) historyLine(respondentID string, i int, record *history.RedactedCommand,
+	fileName string, shellType shell.Type, firstTimestamp time.Time) store.HistoryLine {
+	switch r.HistoryDetail {
+	case CommandsOnly, CommandsAndSubcommands:
+		line := store.HistoryLine{
+			RespondentID: respondentID,
+			QuestionID:   string(r.Question.ID),
+			FileName:     fileName,
+			ShellType:    shellType,
+			LineNum:      i,
+			Command:      record.Command,
+		}
+		if r.HistoryDetail == CommandsAndSubcommands {
+			line.Subcommand = record.Subcommand
+			line.SubcommandPath = record.SubcommandPath
+		}
+		return line
+	default:
+		hash := record.Hash
+		if r.OmitHashes {
+			hash = ""
+		}
+		timestamp, timezoneOffset, offset := r.commandTime(record.Timestamp, firstTimestamp)
+		return store.HistoryLine{
+			RespondentID:        respondentID,
+			QuestionID:          string(r.Question.ID),
+			FileName:            fileName,
+			ShellType:           shellType,
+			LineNum:             i,
+			Command:             record.Command,
+			Subcommand:          record.Subcommand,
+			SubcommandPath:      record.SubcommandPath,
+			Options:             record.Options,
+			Wrappers:            record.Wrappers,
+			EnvVars:             record.EnvVars,
+			Position:            record.Position,
+			PipelinePosition:    record.PipelinePosition,
+			Operator:            record.Operator,
+			Hash:                hash,
+			Length:              record.Length,
+			CommandTimestamp:    timestamp,
+			TimezoneOffsetHours: timezoneOffset,
+			CommandOffset:       offset,
+			TimestampPrecision:  string(r.timestampPrecision()),
+			CommandDuration:     record.Duration,
+			ExitStatus:          record.ExitStatus,
+			Directory:           record.Directory,
+			Session:             record.Session,
+		}
+	}
+}
+
+// timestampPrecision returns how precisely the user chose to share command times
+func (r Answer) timestampPrecision() history.TimestampPrecision {
+	if len(r.TimestampPrecision) == 0 {
+		return history.ExactTimestamps
+	}
+	return r.TimestampPrecision
+}
+
+// commandTime returns when a command ran in UTC, coarsened to the precision the user
+// chose, with the timezone offset in whole hours, or if they chose relative times, how
+// long after the first command it ran
+func (r Answer) commandTime(timestamp time.Time, first time.Time) (time.Time, int, time.Duration) {
+	precision := r.timestampPrecision()
+	if precision == history.RelativeTimestamps {
+		return time.Time{}, 0, history.RelativeTimestamp(timestamp, first)
+	}
+	coarse, timezoneOffset := history.CoarsenTimestamp(timestamp, precision)
+	return coarse, timezoneOffset, 0
+}
+
+// getCommandCounts returns how many times each command in the history was used, if
+// the user chose to upload only that
+func (r Answer) getCommandCounts(respondentID string) []store.CommandCount {
+	commandCounts := make([]store.CommandCount, 0)
+	if r.HistoryDetail != CountsOnly {
+		return commandCounts
+	}
+
+	var counts []history.CommandCount
+	var shellType shell.Type
+	if r.History != nil {
+		counts = r.History.CommandCounts()
+		shellType = r.History.ShellType
+	} else if r.OpenHistoryStream != nil {
+		stream, err := r.OpenHistoryStream()
+		if err != nil {
+			log.Println("Error reading history file", err)
+			return commandCounts
+		}
+		if counts, err = stream.CommandCounts(); err != nil {
+			log.Println("Error reading history file", err)
+			return commandCounts
+		}
+		shellType = stream.ShellType
+	}
+
+	for _, count := range counts {
+		commandCounts = append(commandCounts, store.CommandCount{
+			RespondentID: respondentID,
+			QuestionID:   string(r.Question.ID),
+			ShellType:    shellType,
+			Command:      count.Command,
+			Count:        count.Count,
+		})
+	}
+	return commandCounts
 }
@@ -0,0 +1,191 @@
+package io
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/history"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+	"github.com/warpdotdev/warp-cli-survey/store"
+)
+
+func historyAnswer(detail HistoryDetail) *Answer {
+	return &Answer{Question: file(), HistoryDetail: detail, History: &history.ShellHistory{
+		FileName:  ".zsh_history",
+		ShellType: shell.Zsh,
+		RedactedLines: []*history.RedactedCommand{
+			history.RedactCommand(shell.Zsh, []string{": 1584112360:2;git remote add origin url"}),
+			history.RedactCommand(shell.Zsh, []string{": 1584112361:0;git status --short"}),
+			history.RedactCommand(shell.Zsh, []string{": 1584112362:0;ls -la"}),
+		}}}
+}
+
+func TestResponseFullDetail(t *testing.T) {
+	response := historyAnswer(FullDetail).Response("respondent", 3)
+	assert.Equal(t, 3, len(response.HistoryLines))
+	assert.Equal(t, []string{"remote", "add"}, response.HistoryLines[0].SubcommandPath)
+	assert.Equal(t, []string{"short"}, response.HistoryLines[1].Options)
+	assert.NotEmpty(t, response.HistoryLines[1].Hash)
+	assert.Equal(t, 0, len(response.CommandCounts))
+}
+
+func TestResponseCommandsAndSubcommands(t *testing.T) {
+	response := historyAnswer(CommandsAndSubcommands).Response("respondent", 3)
+	assert.Equal(t, store.HistoryLine{
+		RespondentID:   "respondent",
+		QuestionID:     "id3",
+		FileName:       ".zsh_history",
+		ShellType:      shell.Zsh,
+		LineNum:        0,
+		Command:        "git",
+		Subcommand:     "remote",
+		SubcommandPath: []string{"remote", "add"},
+	}, response.HistoryLines[0])
+}
+
+func TestResponseCommandsOnly(t *testing.T) {
+	response := historyAnswer(CommandsOnly).Response("respondent", 3)
+	assert.Equal(t, 3, len(response.HistoryLines))
+	for _, line := range response.HistoryLines {
+		assert.Empty(t, line.Subcommand)
+		assert.Empty(t, line.Options)
+		assert.Empty(t, line.Hash)
+		assert.True(t, line.CommandTimestamp.IsZero())
+	}
+	assert.Equal(t, "ls", response.HistoryLines[2].Command)
+}
+
+func TestResponseCountsOnly(t *testing.T) {
+	response := historyAnswer(CountsOnly).Response("respondent", 3)
+	assert.Equal(t, 0, len(response.HistoryLines))
+	assert.Equal(t, []store.CommandCount{
+		{RespondentID: "respondent", QuestionID: "id3", ShellType: shell.Zsh, Command: "git", Count: 2},
+		{RespondentID: "respondent", QuestionID: "id3", ShellType: shell.Zsh, Command: "ls", Count: 1},
+	}, response.CommandCounts)
+}
+
+func TestResponseTimestampPrecision(t *testing.T) {
+	answer := historyAnswer(FullDetail)
+	response := answer.Response("respondent", 3)
+	assert.Equal(t, time.Unix(1584112361, 0).UTC(), response.HistoryLines[1].CommandTimestamp)
+	assert.Equal(t, time.UTC, response.HistoryLines[1].CommandTimestamp.Location())
+	assert.Equal(t, string(history.ExactTimestamps), response.HistoryLines[1].TimestampPrecision)
+
+	answer.TimestampPrecision = history.RelativeTimestamps
+	response = answer.Response("respondent", 3)
+	assert.True(t, response.HistoryLines[2].CommandTimestamp.IsZero())
+	assert.Equal(t, 0, response.HistoryLines[2].TimezoneOffsetHours)
+	assert.Equal(t, 2*time.Second, response.HistoryLines[2].CommandOffset)
+}
+
+func TestResponseOmitsOriginalCommands(t *testing.T) {
+	answer := historyAnswer(FullDetail)
+	answer.History.RedactedLines = append(answer.History.RedactedLines,
+		history.RedactCommand(shell.Zsh, []string{": 1584112363:0;curl --user admin https://internal.acme.dev"}))
+	uploaded, err := json.Marshal(answer.Response("respondent", 3))
+	assert.Nil(t, err)
+	assert.Contains(t, string(uploaded), "curl")
+	assert.NotContains(t, string(uploaded), "admin")
+	assert.NotContains(t, string(uploaded), "acme")
+}
+
+type recordingStorer struct {
+	responses []store.Response
+}
+
+func (s *recordingStorer) Write(response store.Response) {
+	s.responses = append(s.responses, response)
+}
+
+func TestWriteBatchesHistoryLines(t *testing.T) {
+	answer := historyAnswer(FullDetail)
+	line := answer.History.RedactedLines[0]
+	for len(answer.History.RedactedLines) < 2*store.HistoryBatchSize+1 {
+		answer.History.RedactedLines = append(answer.History.RedactedLines, line)
+	}
+
+	storage := &recordingStorer{}
+	answer.Write(storage, "respondent", 3)
+	assert.Equal(t, 3, len(storage.responses))
+	assert.Equal(t, store.HistoryBatchSize, len(storage.responses[0].HistoryLines))
+	assert.Equal(t, 1, len(storage.responses[0].Answers))
+	assert.Equal(t, store.HistoryBatchSize, len(storage.responses[1].HistoryLines))
+	assert.Equal(t, 0, len(storage.responses[1].Answers))
+	assert.Equal(t, 1, len(storage.responses[2].HistoryLines))
+	assert.Equal(t, 2*store.HistoryBatchSize, storage.responses[2].HistoryLines[0].LineNum)
+	assert.Equal(t, "id3", storage.responses[2].QuestionID)
+}
+
+func TestWriteStreamsHistoryFile(t *testing.T) {
+	historyFile, err := ioutil.TempFile("", ".zsh_history")
+	assert.Nil(t, err)
+	defer os.Remove(historyFile.Name())
+	for i := 0; i < 2*store.HistoryBatchSize+1; i++ {
+		historyFile.WriteString(": " + strconv.Itoa(1584112360+i) + ":0;git status --short\n")
+	}
+	historyFile.Close()
+
+	answer := &Answer{Question: file(), HistoryDetail: FullDetail, TimestampPrecision: history.RelativeTimestamps,
+		OpenHistoryStream: func() (*history.HistoryStream, error) {
+			return history.StreamHistoryFile(historyFile.Name(), shell.Zsh)
+		}}
+	storage := &recordingStorer{}
+	answer.Write(storage, "respondent", 3)
+	assert.Equal(t, 3, len(storage.responses))
+	assert.Equal(t, store.HistoryBatchSize, len(storage.responses[0].HistoryLines))
+	assert.Equal(t, 1, len(storage.responses[0].Answers))
+	assert.Equal(t, store.HistoryBatchSize, len(storage.responses[1].HistoryLines))
+	last := storage.responses[2].HistoryLines[0]
+	assert.Equal(t, 2*store.HistoryBatchSize, last.LineNum)
+	assert.Equal(t, "git", last.Command)
+	assert.Equal(t, shell.Type(shell.Zsh), last.ShellType)
+	assert.Equal(t, time.Duration(2*store.HistoryBatchSize)*time.Second, last.CommandOffset)
+
+	answer.HistoryDetail = CountsOnly
+	storage = &recordingStorer{}
+	answer.Write(storage, "respondent", 3)
+	assert.Equal(t, 1, len(storage.responses))
+	assert.Equal(t, 0, len(storage.responses[0].HistoryLines))
+	assert.Equal(t, []store.CommandCount{{RespondentID: "respondent", QuestionID: "id3", ShellType: shell.Zsh,
+		Command: "git", Count: 2*store.HistoryBatchSize + 1}}, storage.responses[0].CommandCounts)
+}
+
+func TestWriteWithoutHistory(t *testing.T) {
+	storage := &recordingStorer{}
+	answer := &Answer{Question: file(), Text: "No"}
+	answer.Write(storage, "respondent", 3)
+	assert.Equal(t, []store.Response{answer.Response("respondent", 3)}, storage.responses)
+}
+
+func TestResponseAttributesMergedLines(t *testing.T) {
+	bash := &history.ShellHistory{FileName: ".bash_history", ShellType: shell.Bash,
+		RedactedLines: []*history.RedactedCommand{history.RedactCommand(shell.Bash, []string{"make"})}}
+	answer := historyAnswer(CommandsOnly)
+	answer.History = history.MergeHistories([]*history.ShellHistory{answer.History, bash})
+
+	response := answer.Response("respondent", 3)
+	assert.Equal(t, 4, len(response.HistoryLines))
+	assert.Equal(t, "make", response.HistoryLines[0].Command)
+	assert.Equal(t, ".bash_history", response.HistoryLines[0].FileName)
+	assert.Equal(t, shell.Type(shell.Bash), response.HistoryLines[0].ShellType)
+	assert.Equal(t, ".zsh_history", response.HistoryLines[1].FileName)
+	assert.Equal(t, shell.Type(shell.Zsh), response.HistoryLines[1].ShellType)
+}
+
+func TestResponseIncludesSessions(t *testing.T) {
+	answer := historyAnswer(FullDetail)
+	answer.History.RedactedLines[0].Session = "A"
+
+	response := answer.Response("respondent", 3)
+	assert.Equal(t, "A", response.HistoryLines[0].Session)
+	assert.Equal(t, "", response.HistoryLines[1].Session)
+
+	answer.HistoryDetail = CommandsOnly
+	response = answer.Response("respondent", 3)
+	assert.Equal(t, "", response.HistoryLines[0].Session)
+}
@@ -26,6 +26,25 @@ const (
 	File = "File"
 )
 
+// HistoryDetail is how much detail of their shell history a user chose to upload
+type HistoryDetail string
+
+const (
+	// FullDetail uploads every redacted command with its subcommands, option names,
+	// timing and the rest of the detail we have
+	FullDetail HistoryDetail = "FullDetail"
+
+	// CommandsAndSubcommands uploads the name and subcommands of each command
+	CommandsAndSubcommands = "CommandsAndSubcommands"
+
+	// CommandsOnly uploads just the name of each command
+	CommandsOnly = "CommandsOnly"
+
+	// CountsOnly uploads how many times each command was used, not the commands
+	// themselves
+	CountsOnly = "CountsOnly"
+)
+
 // Question models a question in the survey
 type Question struct {
 	// ID is the unique identifier of the question
@@ -62,6 +81,19 @@ type Question struct {
 	// Accepts a an optional history file.  If omitted, uses the default history file
 	// for the shell type.
 	GetShellHistoryFn func(shellType shell.Type, historyFile *string) *history.ShellHistory
+
+	// GetBuiltinHistoryFn is called for file type questions to fetch the shell history
+	// with the shell's own history builtin when there is no history file to read. It
+	// runs the user's shell, so it is only called once they agree to BuiltinHistoryConsent.
+	GetBuiltinHistoryFn func(shellType shell.Type) *history.ShellHistory
+
+	// BuiltinHistoryConsent asks the user before GetBuiltinHistoryFn runs their shell.
+	// It is formatted with the shell type and the command that will be run.
+	BuiltinHistoryConsent string
+
+	// HistoryDetails are the levels of detail offered by the first Values of a file
+	// question. Choosing a later value declines the upload.
+	HistoryDetails []HistoryDetail
 }
 
 // Parse accepts an answer from the user and parses it into an io.Response
@@ -117,12 +149,16 @@ func (q Question) Parse(answer string) *Answer {
 
 	if q.Type == File {
 		choiceNum, err := strconv.Atoi(strings.TrimSpace(answer))
-		if err != nil || choiceNum < 1 || choiceNum > len(q.Values)+1 {
+		if err != nil || choiceNum < 1 || choiceNum > len(q.Values) {
 			return &Answer{Question: q, IsDone: false, Message: "Please choose an available option."}
 		}
 
-		if choiceNum == 1 {
-			return &Answer{Question: q, IsDone: true, Text: q.Values[choiceNum-1], PreviewFile: true}
+		if choiceNum <= len(q.HistoryDetails) {
+			return &Answer{Question: q, IsDone: true, Text: q.Values[choiceNum-1], PreviewFile: true,
+				HistoryDetail: q.HistoryDetails[choiceNum-1]}
+		} else if choiceNum == 1 {
+			return &Answer{Question: q, IsDone: true, Text: q.Values[choiceNum-1], PreviewFile: true,
+				HistoryDetail: FullDetail}
 		} else {
 			return &Answer{Question: q, IsDone: true, Text: q.Values[choiceNum-1], PreviewFile: false,
 				CustomThanks: "Ok, no problem we won't upload it."}
@@ -1,9 +1,11 @@
 package io
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/shell"
 )
 
 func freeForm() Question {
@@ -25,7 +27,7 @@ func multipleChoice() Question {
 
 func file() Question {
 	return Question{ID: "id3", Text: "q3",
-		Type: File}
+		Type: File, Values: []string{"Yes", "No"}}
 }
 
 func TestFreeForm(t *testing.T) {
@@ -76,3 +78,27 @@ func TestParseMultiSelectOnlyOne(t *testing.T) {
 	assert.Equal(t, 1, len(r.SelectedOptions))
 	assert.Equal(t, "c", r.SelectedOptions[0])
 }
+
+func TestFileHistoryDetail(t *testing.T) {
+	q := Question{ID: "id4", Text: "q4", Type: File,
+		Values:         []string{"Full", "Commands", "Counts", "No"},
+		HistoryDetails: []HistoryDetail{FullDetail, CommandsOnly, CountsOnly}}
+	r := q.Parse("2")
+	assert.Equal(t, true, r.PreviewFile)
+	assert.Equal(t, HistoryDetail(CommandsOnly), r.HistoryDetail)
+
+	r = q.Parse("4")
+	assert.Equal(t, false, r.PreviewFile)
+
+	r = q.Parse("5")
+	assert.Equal(t, false, r.IsDone)
+}
+
+func TestBuiltinHistoryConsent(t *testing.T) {
+	q := questionMap[shellHistory]
+	consent := fmt.Sprintf(q.BuiltinHistoryConsent, shell.Zsh, "zsh -ic 'fc -l -t %s 1'")
+	assert.NotContains(t, consent, "%!")
+	assert.Contains(t, consent, "zsh -ic 'fc -l -t %s 1'")
+	assert.Contains(t, consent, "[y / N]")
+	assert.NotNil(t, q.GetBuiltinHistoryFn)
+}
@@ -112,12 +112,25 @@ var questionMap = map[QuestionID]Question{
 ** But we get that this could be scary, so it's totally up to you if you share (although it would be helpful!)`,
 		Type: File,
 		Values: []string{
-			"Yes (shows a preview before submitting)",
+			"Yes, with full redacted detail (shows a preview before submitting)",
+			"Yes, but only commands and their subcommands (shows a preview)",
+			"Yes, but only command names (shows a preview)",
+			"Yes, but only how many times I used each command (shows a preview)",
 			"No"},
-		GetShellHistoryFn: history.GetRedactedShellHistory,
+		HistoryDetails:      []HistoryDetail{FullDetail, CommandsAndSubcommands, CommandsOnly, CountsOnly},
+		GetShellHistoryFn:   history.GetRedactedShellHistory,
+		GetBuiltinHistoryFn: history.RedactBuiltinHistory,
+		BuiltinHistoryConsent: `We couldn't find a %[1]s history file we can read. We can ask %[1]s for your history instead by running:
+
+    %[2]s
+
+** This starts %[1]s the way your terminal does, so it runs your startup files, prints your history and exits.
+** Nothing else is run, and the output is redacted just like a history file before you see the preview.
+Is it OK to run it? [y / N]`,
 		ShouldShowFn: func(responsesSoFar map[QuestionID]*Answer) bool {
 			shellType := shell.GetShellType(responsesSoFar["shell_type"].Text)
-			return shellType == shell.Bash || shellType == shell.Zsh
+			return shellType == shell.Bash || shellType == shell.Zsh || shellType == shell.Fish ||
+				shellType == shell.PowerShell || shellType == shell.Nu
 		}},
 	terminalType: {ID: terminalType, Text: "What terminal do you typically use?",
 		Type: MultipleChoice, MultiSelect: true, ShowOther: true,
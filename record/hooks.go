@@ -0,0 +1,281 @@
+package record
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// The hook is added to each shell's startup file between these lines, so it can be
+// found again to replace or remove it
+const (
+	hookStart = "# >>> dsurvey record >>>"
+	hookEnd   = "# <<< dsurvey record <<<"
+)
+
+// recorderPlaceholder is replaced in a hook script by the quoted path of the dsurvey
+// binary
+const recorderPlaceholder = "{{recorder}}"
+
+// hook is how to record the commands run in a shell
+type hook struct {
+	// startupFile returns the file the hook is added to, with the environment given
+	// by getenv
+	startupFile func(getenv func(string) string) string
+
+	// quote quotes the path of the recorder for the shell
+	quote func(path string) string
+
+	// script calls dsurvey record log after every command line
+	script string
+}
+
+// hookShells are the shells we can record, in the order they're listed
+var hookShells = []shell.Type{shell.Bash, shell.Zsh, shell.Fish}
+
+var hooks = map[shell.Type]hook{
+	shell.Bash: {startupFile: bashStartupFile, quote: posixQuote, script: bashHook},
+	shell.Zsh:  {startupFile: zshStartupFile, quote: posixQuote, script: zshHook},
+	shell.Fish: {startupFile: fishStartupFile, quote: fishQuote, script: fishHook},
+}
+
+// bash has no preexec hook, so the DEBUG trap notes when the first command of a line
+// starts and PROMPT_COMMAND records the line from the history once it finishes. The
+// trap is only armed by the last command of PROMPT_COMMAND, so the commands already in
+// it aren't mistaken for the start of the next line. Lines bash leaves out of its
+// history, e.g. with HISTCONTROL=ignorespace, aren't recorded either. A DEBUG trap set
+// before the hook keeps running after the hook's, with $? and $_ as they were, and
+// sourcing the startup file again doesn't add the hook twice.
+const bashHook = `if [[ $- == *i* ]] && [ -x {{recorder}} ]; then
+  __dsurvey_preexec() {
+    __dsurvey_status=$1 __dsurvey_last_arg=$2
+    if [ -n "$__dsurvey_at_prompt" ] && [ "$BASH_COMMAND" != __dsurvey_precmd ]; then
+      __dsurvey_at_prompt=
+      __dsurvey_start=${EPOCHREALTIME:-$(date +%s)}
+    fi
+  }
+  __dsurvey_precmd() {
+    local exit_status=$? entry pattern='^ *([0-9]+)[* ] (.*)$'
+    if [ -n "$__dsurvey_start" ]; then
+      entry=$(HISTTIMEFORMAT= builtin history 1)
+      if [[ $entry =~ $pattern && ${BASH_REMATCH[1]} != "$__dsurvey_last" ]]; then
+        __dsurvey_last=${BASH_REMATCH[1]}
+        {{recorder}} record log --shell bash --status "$exit_status" --start "$__dsurvey_start" \
+          --end "${EPOCHREALTIME:-$(date +%s)}" --directory "$PWD" -- "${BASH_REMATCH[2]}" 2>/dev/null
+      fi
+    fi
+    __dsurvey_start=
+    __dsurvey_at_prompt=
+  }
+  __dsurvey_return() { return "$1"; }
+  __dsurvey_previous_trap() { [ $# -eq 4 ] && __dsurvey_debug_trap=$3; }
+  if [[ $(trap -p DEBUG) != *__dsurvey_preexec* ]]; then
+    __dsurvey_debug_trap=
+    eval "__dsurvey_previous_trap $(trap -p DEBUG)"
+    if [ -n "$__dsurvey_debug_trap" ]; then
+      __dsurvey_debug_trap="; __dsurvey_return \"\$__dsurvey_status\" \"\$__dsurvey_last_arg\"; $__dsurvey_debug_trap"
+    fi
+    trap "__dsurvey_preexec \"\$?\" \"\$_\"$__dsurvey_debug_trap" DEBUG
+  fi
+  if [[ $PROMPT_COMMAND != *__dsurvey_precmd* ]]; then
+    PROMPT_COMMAND="__dsurvey_precmd${PROMPT_COMMAND:+; $PROMPT_COMMAND}; __dsurvey_at_prompt=1"
+  fi
+fi
+`
+
+// zsh passes the command line to preexec. The precmd hook goes first so it sees the
+// exit status before other hooks run anything.
+const zshHook = `if [[ -x {{recorder}} ]]; then
+  zmodload zsh/datetime 2>/dev/null
+  __dsurvey_preexec() {
+    [[ -o histignorespace && $1 == ' '* ]] && return
+    __dsurvey_command=$1
+    __dsurvey_start=${EPOCHREALTIME:-$(date +%s)}
+  }
+  __dsurvey_precmd() {
+    local exit_status=$?
+    if [[ -n $__dsurvey_start ]]; then
+      {{recorder}} record log --shell zsh --status "$exit_status" --start "$__dsurvey_start" \
+        --end "${EPOCHREALTIME:-$(date +%s)}" --directory "$PWD" -- "$__dsurvey_command" 2>/dev/null
+    fi
+    __dsurvey_start=
+  }
+  autoload -Uz add-zsh-hook
+  add-zsh-hook preexec __dsurvey_preexec
+  precmd_functions=(__dsurvey_precmd $precmd_functions)
+fi
+`
+
+// fish passes the command line to fish_postexec and sets CMD_DURATION, in
+// milliseconds. Lines fish leaves out of its history aren't recorded either.
+const fishHook = `if status is-interactive; and test -x {{recorder}}
+    function __dsurvey_postexec --on-event fish_postexec
+        set -l exit_status $status
+        if set -q fish_private_mode; or test -z "$argv[1]"; or string match -q -- ' *' $argv[1]
+            return
+        end
+        {{recorder}} record log --shell fish --status $exit_status --duration $CMD_DURATION \
+            --directory $PWD -- $argv[1] 2>/dev/null
+    end
+end
+`
+
+func bashStartupFile(getenv func(string) string) string {
+	return filepath.Join(getenv("HOME"), ".bashrc")
+}
+
+func zshStartupFile(getenv func(string) string) string {
+	zdotdir := getenv("ZDOTDIR")
+	if len(zdotdir) == 0 {
+		zdotdir = getenv("HOME")
+	}
+	return filepath.Join(zdotdir, ".zshrc")
+}
+
+// fish reads every file in conf.d, so the hook gets a file of its own
+func fishStartupFile(getenv func(string) string) string {
+	configHome := getenv("XDG_CONFIG_HOME")
+	if len(configHome) == 0 {
+		configHome = filepath.Join(getenv("HOME"), ".config")
+	}
+	return filepath.Join(configHome, "fish", "conf.d", "dsurvey_record.fish")
+}
+
+// posixQuote quotes a path for bash and zsh
+func posixQuote(path string) string {
+	return shellquote.Join(path)
+}
+
+// fishQuote quotes a path for fish, which only treats \' and \\ as escapes in single
+// quotes
+func fishQuote(path string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(path) + "'"
+}
+
+// hookScript returns the lines added to a shell's startup file to run recorder after
+// every command line
+func hookScript(shellType shell.Type, recorder string) string {
+	h := hooks[shellType]
+	script := strings.Replace(h.script, recorderPlaceholder, h.quote(recorder), -1)
+	return hookStart + "\n# Added by dsurvey record install, remove it with dsurvey record uninstall\n" +
+		script + hookEnd + "\n"
+}
+
+// installHook adds the hook for a shell to its startup file, replacing any hook
+// already there, and returns the file
+func installHook(getenv func(string) string, shellType shell.Type, recorder string) (string, error) {
+	path := hooks[shellType].startupFile(getenv)
+	contents, err := readStartupFile(path)
+	if err != nil {
+		return path, err
+	}
+	contents, _ = removeHook(contents)
+	if len(contents) > 0 && !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	if len(contents) > 0 {
+		contents += "\n"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return path, err
+	}
+	return path, writeStartupFile(path, contents+hookScript(shellType, recorder))
+}
+
+// uninstallHook removes the hook for a shell from its startup file, returning the file
+// and whether there was a hook to remove. A startup file left empty is deleted.
+func uninstallHook(getenv func(string) string, shellType shell.Type) (string, bool, error) {
+	path := hooks[shellType].startupFile(getenv)
+	contents, err := readStartupFile(path)
+	if err != nil {
+		return path, false, err
+	}
+	contents, removed := removeHook(contents)
+	if !removed {
+		return path, false, nil
+	}
+	if len(strings.TrimSpace(contents)) == 0 {
+		return path, true, os.Remove(path)
+	}
+	return path, true, writeStartupFile(path, contents)
+}
+
+// hookInstalled returns the startup file the hook for a shell goes in, and whether
+// it's there
+func hookInstalled(getenv func(string) string, shellType shell.Type) (string, bool) {
+	path := hooks[shellType].startupFile(getenv)
+	contents, err := readStartupFile(path)
+	return path, err == nil && strings.Contains(contents, hookStart)
+}
+
+// debugTrapPattern matches a line of a bash startup file that sets a DEBUG trap, or
+// loads one of the tools known to
+var debugTrapPattern = regexp.MustCompile(`\btrap\b.*\bDEBUG\b|bash[-_]preexec|atuin init bash|iterm2_shell_integration`)
+
+// debugTraps returns the lines of a bash startup file outside the hook that look like
+// they set a DEBUG trap, split into those before the hook and those after it. Traps set
+// from other files aren't found.
+func debugTraps(contents string) (before []string, after []string) {
+	inHook, afterHook := false, false
+	for _, line := range strings.Split(contents, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == hookStart:
+			inHook = true
+		case trimmed == hookEnd:
+			inHook, afterHook = false, true
+		case inHook || strings.HasPrefix(trimmed, "#") || !debugTrapPattern.MatchString(trimmed):
+		case afterHook:
+			after = append(after, trimmed)
+		default:
+			before = append(before, trimmed)
+		}
+	}
+	return before, after
+}
+
+// removeHook removes the lines between the hook markers, and the blank line installHook
+// adds before them
+func removeHook(contents string) (string, bool) {
+	start := strings.Index(contents, hookStart)
+	if start < 0 {
+		return contents, false
+	}
+	end := strings.Index(contents[start:], hookEnd)
+	if end < 0 {
+		return contents, false
+	}
+	end += start + len(hookEnd)
+	if end < len(contents) && contents[end] == '\n' {
+		end++
+	}
+	before := contents[:start]
+	if strings.HasSuffix(before, "\n\n") {
+		before = before[:len(before)-1]
+	}
+	return before + contents[end:], true
+}
+
+// readStartupFile returns the contents of a startup file, or nothing if it doesn't
+// exist yet
+func readStartupFile(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(contents), err
+}
+
+// writeStartupFile writes a startup file, keeping its permissions if it exists
+func writeStartupFile(path string, contents string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return ioutil.WriteFile(path, []byte(contents), mode)
+}
@@ -0,0 +1,225 @@
+package record
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/history"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// recorderTestEnv is set when the hooks run the test binary as the recorder
+const recorderTestEnv = "DSURVEY_RECORDER_TEST"
+
+func TestMain(m *testing.M) {
+	// The hooks run the recorder as <binary> record log ...
+	if os.Getenv(recorderTestEnv) == "1" && len(os.Args) > 3 && os.Args[1] == "record" && os.Args[2] == "log" {
+		if err := Log(os.Args[3:]); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func testEnv(home string) func(string) string {
+	return func(name string) string {
+		if name == "HOME" {
+			return home
+		}
+		return ""
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+	getenv := testEnv(home)
+	bashrc := filepath.Join(home, ".bashrc")
+	assert.Nil(t, ioutil.WriteFile(bashrc, []byte("alias ll='ls -l'"), 0600))
+
+	path, err := installHook(getenv, shell.Bash, "/opt/my tools/dsurvey")
+	assert.Nil(t, err)
+	assert.Equal(t, bashrc, path)
+	path, err = installHook(getenv, shell.Bash, "/usr/local/bin/dsurvey")
+	assert.Nil(t, err)
+	contents, err := ioutil.ReadFile(bashrc)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(contents), "alias ll='ls -l'\n\n"+hookStart+"\n"))
+	assert.Equal(t, 1, strings.Count(string(contents), hookStart))
+	assert.Contains(t, string(contents), "/usr/local/bin/dsurvey record log --shell bash")
+	assert.NotContains(t, string(contents), "my tools")
+	info, err := os.Stat(bashrc)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, installed := hookInstalled(getenv, shell.Bash)
+	assert.True(t, installed)
+	_, installed = hookInstalled(getenv, shell.Zsh)
+	assert.False(t, installed)
+
+	_, removed, err := uninstallHook(getenv, shell.Bash)
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	contents, err = ioutil.ReadFile(bashrc)
+	assert.Nil(t, err)
+	assert.Equal(t, "alias ll='ls -l'\n", string(contents))
+	_, removed, err = uninstallHook(getenv, shell.Bash)
+	assert.Nil(t, err)
+	assert.False(t, removed)
+}
+
+func TestInstallFishHook(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+	getenv := testEnv(home)
+
+	path, err := installHook(getenv, shell.Fish, "/opt/it's/dsurvey")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(home, ".config", "fish", "conf.d", "dsurvey_record.fish"), path)
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), `test -x '/opt/it\'s/dsurvey'`)
+
+	// The hook's file is removed with it
+	_, removed, err := uninstallHook(getenv, shell.Fish)
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// runHooks runs a script in a throwaway shell with the hook installed, and the test
+// binary as the recorder, and returns what it recorded. env is added to the shell's
+// environment, and startup to its startup file ahead of the hook.
+func runHooks(t *testing.T, shellType shell.Type, env []string, startup string, script string,
+	args ...string) *history.ShellHistory {
+	if _, err := exec.LookPath(args[0]); err != nil {
+		t.Skip(args[0], "is not installed")
+	}
+	home, err := ioutil.TempDir("", "home")
+	assert.Nil(t, err)
+	defer os.RemoveAll(home)
+	recorder, err := filepath.Abs(os.Args[0])
+	assert.Nil(t, err)
+	if len(startup) > 0 {
+		path := hooks[shellType].startupFile(testEnv(home))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(startup), 0644))
+	}
+	_, err = installHook(testEnv(home), shellType, recorder)
+	assert.Nil(t, err)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = home
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Env = append(os.Environ(), "HOME="+home, "ZDOTDIR=", "XDG_CONFIG_HOME=", "XDG_DATA_HOME=",
+		"HISTCONTROL=ignorespace", "PROMPT_COMMAND=", recorderTestEnv+"=1")
+	cmd.Env = append(cmd.Env, env...)
+	output, err := cmd.CombinedOutput()
+	assert.Nil(t, err, string(output))
+
+	recorded := history.RedactRecordedHistory(
+		filepath.Join(home, ".local", "share", "warp-survey", "recorded_commands.jsonl"), shellType)
+	assert.NotNil(t, recorded, string(output))
+	return recorded
+}
+
+func TestBashHook(t *testing.T) {
+	recorded := runHooks(t, shell.Bash, nil, "", "ls /nonexistent\n\n ls secret\ngit status | cat\nfor i in 1 2; do\n  true\ndone\n",
+		"bash", "-i")
+	if recorded == nil {
+		return
+	}
+	commands := make([]string, 0)
+	for _, r := range recorded.RedactedLines {
+		commands = append(commands, r.Command)
+	}
+	assert.Equal(t, []string{"ls", "git", "cat", "true"}, commands)
+	assert.Equal(t, 2, *recorded.RedactedLines[0].ExitStatus)
+	assert.Equal(t, 0, *recorded.RedactedLines[1].ExitStatus)
+	assert.Equal(t, "~", recorded.RedactedLines[0].Directory)
+	assert.False(t, recorded.RedactedLines[0].Timestamp.IsZero())
+	assert.True(t, recorded.RedactedLines[0].Duration > 0)
+}
+
+func TestBashHookWithPromptCommand(t *testing.T) {
+	// The existing PROMPT_COMMAND runs after the hook's, at the prompt, and isn't
+	// counted in the time the next command took
+	recorded := runHooks(t, shell.Bash, []string{"PROMPT_COMMAND=sleep 0.5"}, "", "true\nls\n",
+		"bash", "-i")
+	if recorded == nil {
+		return
+	}
+	assert.Equal(t, 2, len(recorded.RedactedLines))
+	for _, r := range recorded.RedactedLines {
+		assert.True(t, r.Duration < 250*time.Millisecond, r.Duration)
+	}
+}
+
+func TestBashHookChainsDebugTrap(t *testing.T) {
+	trapLog, err := ioutil.TempFile("", "debug_trap")
+	assert.Nil(t, err)
+	trapLog.Close()
+	defer os.Remove(trapLog.Name())
+
+	// The existing trap still runs, and sees $? and $_ from the command before
+	recorded := runHooks(t, shell.Bash, nil, `trap 'echo "$? $_ $BASH_COMMAND" >> `+trapLog.Name()+`' DEBUG`+"\n",
+		"false\ntrue x\n", "bash", "-i")
+	if recorded == nil {
+		return
+	}
+	commands := make([]string, 0)
+	for _, r := range recorded.RedactedLines {
+		commands = append(commands, r.Command)
+	}
+	assert.Equal(t, []string{"false", "true"}, commands)
+	trapped, err := ioutil.ReadFile(trapLog.Name())
+	assert.Nil(t, err)
+	assert.Contains(t, string(trapped), "1 false true x\n")
+	assert.Contains(t, string(trapped), "1 false __dsurvey_precmd\n")
+}
+
+func TestDebugTraps(t *testing.T) {
+	contents := "trap 'echo hi' DEBUG\n# trap - DEBUG\n" + hookScript(shell.Bash, "/usr/local/bin/dsurvey") +
+		"source ~/.bash-preexec.sh\neval \"$(atuin init bash)\"\nalias ll='ls -l'\n"
+	before, after := debugTraps(contents)
+	assert.Equal(t, []string{"trap 'echo hi' DEBUG"}, before)
+	assert.Equal(t, []string{"source ~/.bash-preexec.sh", `eval "$(atuin init bash)"`}, after)
+
+	before, after = debugTraps("alias ll='ls -l'\n")
+	assert.Empty(t, before)
+	assert.Empty(t, after)
+}
+
+func TestZshHook(t *testing.T) {
+	// zsh runs the hooks around each line it reads, here they're called directly
+	recorded := runHooks(t, shell.Zsh, nil, "", "", "zsh", "-f", "-c",
+		`setopt histignorespace; source ~/.zshrc; __dsurvey_preexec "git status"; false; __dsurvey_precmd; `+
+			`__dsurvey_preexec " ls secret"; __dsurvey_precmd`)
+	if recorded == nil {
+		return
+	}
+	assert.Equal(t, 1, len(recorded.RedactedLines))
+	assert.Equal(t, "git", recorded.RedactedLines[0].Command)
+	assert.Equal(t, 1, *recorded.RedactedLines[0].ExitStatus)
+}
+
+func TestFishHook(t *testing.T) {
+	recorded := runHooks(t, shell.Fish, nil, "", "", "fish", "--no-config", "--interactive", "-c",
+		`source ~/.config/fish/conf.d/dsurvey_record.fish; false; emit fish_postexec "git status"; `+
+			`emit fish_postexec " ls secret"`)
+	if recorded == nil {
+		return
+	}
+	assert.Equal(t, 1, len(recorded.RedactedLines))
+	assert.Equal(t, "git", recorded.RedactedLines[0].Command)
+}
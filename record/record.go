@@ -0,0 +1,225 @@
+package record
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/warpdotdev/warp-cli-survey/history"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+// Install adds a hook to the startup files of the named shells, or every shell we can
+// record that's installed, which records how long each command line runs and its exit
+// status. Commands are redacted before they're written to the record log, which the
+// survey offers to share with your history.
+func Install(shellNames []string) error {
+	shellTypes, err := parseShells(shellNames, installedShells())
+	if err != nil {
+		return err
+	}
+	if len(shellTypes) == 0 {
+		return errors.New("None of bash, zsh or fish are installed")
+	}
+	recorder, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(recorder); err == nil {
+		recorder = resolved
+	}
+
+	for _, shellType := range shellTypes {
+		path, err := installHook(os.Getenv, shellType, recorder)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Added the", shellType, "hook to", path)
+		if shellType == shell.Bash {
+			printDebugTraps(path)
+		}
+	}
+	fmt.Println("\nCommands you run in new shells will be redacted and recorded in", history.RecordLogPath())
+	if strings.HasPrefix(recorder, os.TempDir()) {
+		fmt.Println("\nThe hooks run", recorder, "and stop recording if it's deleted. Move it somewhere",
+			"that lasts, like ~/bin, and run dsurvey record install from there to keep recording.")
+	}
+	return nil
+}
+
+// Uninstall removes the hooks from the startup files of the named shells, or every
+// shell we can record. The record log is kept.
+func Uninstall(shellNames []string) error {
+	shellTypes, err := parseShells(shellNames, hookShells)
+	if err != nil {
+		return err
+	}
+	removed := false
+	for _, shellType := range shellTypes {
+		path, ok, err := uninstallHook(os.Getenv, shellType)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fmt.Println("Removed the", shellType, "hook from", path)
+			removed = true
+		}
+	}
+	if !removed {
+		fmt.Println("The hooks weren't installed")
+	}
+	if logPath, err := history.GetRecordedHistoryFile(); err == nil {
+		fmt.Println("\nYour recorded commands are still in", logPath, "- delete it if you don't need them.")
+	}
+	return nil
+}
+
+// Status prints which shells have the hook installed and how much has been recorded
+func Status() {
+	for _, shellType := range hookShells {
+		path, installed := hookInstalled(os.Getenv, shellType)
+		if installed {
+			fmt.Println(shellType, "hook installed in", path)
+			if shellType == shell.Bash {
+				printDebugTraps(path)
+			}
+		} else {
+			fmt.Println(shellType, "hook not installed")
+		}
+	}
+
+	logPath, err := history.GetRecordedHistoryFile()
+	if err != nil {
+		fmt.Println("\nNothing has been recorded yet")
+		return
+	}
+	recorded := history.RedactRecordedHistory(logPath, shell.Unknown)
+	if recorded == nil {
+		fmt.Println("\nUnable to read", logPath)
+		return
+	}
+	fmt.Print("\n", len(recorded.RedactedLines), " commands recorded in ", logPath)
+	if first := recorded.FirstTimestamp(); !first.IsZero() {
+		fmt.Print(" since ", first.Local().Format("Jan 2, 2006 15:04"))
+	}
+	fmt.Println()
+}
+
+// printDebugTraps points out the DEBUG traps set in the bash startup file, since the
+// bash hook needs one of its own. A trap set after the hook can replace it, and then
+// nothing is recorded.
+func printDebugTraps(path string) {
+	contents, err := readStartupFile(path)
+	if err != nil {
+		return
+	}
+	before, after := debugTraps(contents)
+	for _, line := range before {
+		fmt.Println("  " + path + " sets a DEBUG trap before the hook (" + line + "), which the hook keeps running")
+	}
+	for _, line := range after {
+		fmt.Println("  Warning: " + path + " sets a DEBUG trap after the hook (" + line + "). Unless it keeps " +
+			"the hook's trap running, as bash-preexec does, bash commands won't be recorded. Run dsurvey record " +
+			"install again to move the hook after it.")
+	}
+}
+
+// Log records a single command line. The hooks run it after every command line as
+// dsurvey record log --shell <shell> --status <exit status> [--start <time>]
+// [--end <time>] [--duration <ms>] [--directory <dir>] -- <command line>, with times
+// in seconds since the epoch. The end defaults to now, and the start to the end less
+// the duration.
+func Log(args []string) error {
+	flags := flag.NewFlagSet("record log", flag.ContinueOnError)
+	shellName := flags.String("shell", "", "the shell that ran the command")
+	exitStatus := flags.Int("status", 0, "the exit status of the command line")
+	start := flags.String("start", "", "when the command line started, in seconds since the epoch")
+	end := flags.String("end", "", "when the command line finished, in seconds since the epoch")
+	durationMs := flags.Int64("duration", 0, "how long the command line ran, in milliseconds")
+	directory := flags.String("directory", "", "the working directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	run := history.CommandRun{
+		ShellType:   shell.GetShellType(*shellName),
+		CommandLine: strings.Join(flags.Args(), " "),
+		ExitStatus:  *exitStatus,
+		Directory:   *directory,
+		Duration:    time.Duration(*durationMs) * time.Millisecond,
+	}
+	if _, ok := hooks[run.ShellType]; !ok {
+		return errors.New("Unable to record commands for shell " + *shellName)
+	}
+	if len(strings.TrimSpace(run.CommandLine)) == 0 {
+		return nil
+	}
+
+	endTime := time.Now()
+	if len(*end) > 0 {
+		var err error
+		if endTime, err = parseEpochTime(*end); err != nil {
+			return err
+		}
+	}
+	run.Start = endTime.Add(-run.Duration)
+	if len(*start) > 0 {
+		var err error
+		if run.Start, err = parseEpochTime(*start); err != nil {
+			return err
+		}
+		run.Duration = endTime.Sub(run.Start)
+	}
+	return history.RecordCommand(history.RecordLogPath(), run)
+}
+
+// parseEpochTime parses seconds since the epoch with an optional fraction, like
+// bash's and zsh's EPOCHREALTIME, which use the locale's decimal separator
+func parseEpochTime(value string) (time.Time, error) {
+	parts := strings.SplitN(strings.Replace(value, ",", ".", 1), ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nanoseconds int64
+	if len(parts) == 2 && len(parts[1]) > 0 {
+		fraction := (parts[1] + "000000000")[:9]
+		if nanoseconds, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(seconds, nanoseconds), nil
+}
+
+// parseShells returns the shells named, or all of fallback if none are
+func parseShells(shellNames []string, fallback []shell.Type) ([]shell.Type, error) {
+	if len(shellNames) == 0 {
+		return fallback, nil
+	}
+	shellTypes := make([]shell.Type, 0, len(shellNames))
+	for _, name := range shellNames {
+		shellType := shell.GetShellType(name)
+		if _, ok := hooks[shellType]; !ok {
+			return nil, errors.New("Unable to record commands for shell " + name + ", try bash, zsh or fish")
+		}
+		shellTypes = append(shellTypes, shellType)
+	}
+	return shellTypes, nil
+}
+
+// installedShells returns the shells we can record that are on the PATH
+func installedShells() []shell.Type {
+	installed := make([]shell.Type, 0)
+	for _, shellType := range hookShells {
+		if _, err := exec.LookPath(strings.ToLower(string(shellType))); err == nil {
+			installed = append(installed, shellType)
+		}
+	}
+	return installed
+}
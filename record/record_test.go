@@ -0,0 +1,57 @@
+package record
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/warpdotdev/warp-cli-survey/history"
+	"github.com/warpdotdev/warp-cli-survey/shell"
+)
+
+func TestParseEpochTime(t *testing.T) {
+	parsed, err := parseEpochTime("1584112360.25")
+	assert.Nil(t, err)
+	assert.True(t, time.Unix(1584112360, 250000000).Equal(parsed))
+	parsed, err = parseEpochTime("1584112360,000001")
+	assert.Nil(t, err)
+	assert.True(t, time.Unix(1584112360, 1000).Equal(parsed))
+	parsed, err = parseEpochTime("1584112360")
+	assert.Nil(t, err)
+	assert.True(t, time.Unix(1584112360, 0).Equal(parsed))
+	_, err = parseEpochTime("yesterday")
+	assert.NotNil(t, err)
+}
+
+func TestLog(t *testing.T) {
+	dataHome, err := ioutil.TempDir("", "data")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dataHome)
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+	os.Setenv("XDG_DATA_HOME", dataHome)
+
+	assert.Nil(t, Log([]string{"--shell", "bash", "--status", "1", "--start", "1584112360.5", "--end", "1584112362",
+		"--", "make", "-j", "4"}))
+	assert.Nil(t, Log([]string{"--shell", "fish", "--duration", "1500", "--", "git status"}))
+	assert.Nil(t, Log([]string{"--shell", "zsh", "--", "  "}))
+	assert.NotNil(t, Log([]string{"--shell", "tcsh", "--", "ls"}))
+	assert.NotNil(t, Log([]string{"--shell", "bash", "--start", "soon", "--", "ls"}))
+
+	recorded := history.RedactRecordedHistory(filepath.Join(dataHome, "warp-survey", "recorded_commands.jsonl"), shell.Bash)
+	assert.NotNil(t, recorded)
+	assert.Equal(t, 2, len(recorded.RedactedLines))
+	makeCommand := recorded.RedactedLines[0]
+	assert.Equal(t, "make", makeCommand.Command)
+	assert.Equal(t, []string{"jobs"}, makeCommand.Options)
+	assert.True(t, time.Unix(1584112360, 500000000).Equal(makeCommand.Timestamp))
+	assert.Equal(t, 1500*time.Millisecond, makeCommand.Duration)
+	assert.Equal(t, 1, *makeCommand.ExitStatus)
+	gitCommand := recorded.RedactedLines[1]
+	assert.Equal(t, "git", gitCommand.Command)
+	assert.Equal(t, 1500*time.Millisecond, gitCommand.Duration)
+	assert.Equal(t, 0, *gitCommand.ExitStatus)
+	assert.Equal(t, shell.Type(shell.Fish), gitCommand.SourceShell)
+}
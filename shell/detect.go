@@ -0,0 +1,122 @@
+package shell
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Number of lines at the start of a history file we look at to guess its format
+const detectLines = 50
+
+// weakConfidence is the most confidence we have in a shell type guessed only from weak
+// markers, which is never enough to overrule the file name or the shell we were asked
+// to read
+const weakConfidence = 0.25
+
+// Markers for each history file format. A line matching one of these is a vote
+// for that shell. Weak markers are commands that are only likely in that shell,
+// rather than part of its file format.
+var historyLineMarkers = []struct {
+	shellType Type
+	regEx     *regexp.Regexp
+	weak      bool
+}{
+	// : <timestamp>:<elapsed>;<command>
+	{Zsh, regexp.MustCompile(`^: \d+:\d+;`), false},
+	// - cmd: <command>
+	//   when: <timestamp>
+	{Fish, regexp.MustCompile(`^- cmd: `), false},
+	{Fish, regexp.MustCompile(`^  (when: \d+|paths:)$`), false},
+	// #<timestamp>
+	{Bash, regexp.MustCompile(`^#\d+$`), false},
+	// Verb-Noun cmdlets. Backtick line continuations aren't counted, as bash's command
+	// substitutions end lines with a backtick too, e.g. kill `cat /tmp/pid`
+	{PowerShell, regexp.MustCompile(`^(Get|Set|New|Remove|Invoke|Select|Where|Write|Import|Start|Stop)-[A-Z][A-Za-z]+`), true},
+	// Escaped newlines in history.txt
+	{Nu, regexp.MustCompile(`<\\n>`), true},
+	// The header of a history.sqlite3 database
+	{Nu, regexp.MustCompile(`^SQLite format 3\x00`), false},
+}
+
+// DetectShellType guesses which shell wrote a history file by looking at the first
+// lines of the file, falling back to the file name when the contents are ambiguous or
+// only weakly suggest another shell. Returns the shell type and a confidence between 0
+// and 1, or Unknown and 0 for an empty file or one with neither a distinctive name nor
+// distinctive lines.
+func DetectShellType(historyFilePath string) (Type, float64) {
+	named := GetShellType(historyFilePath)
+
+	file, err := os.Open(historyFilePath)
+	if err != nil {
+		return named, 0
+	}
+	defer file.Close()
+
+	lines := make([]string, 0, detectLines)
+	reader := bufio.NewReader(file)
+	for len(lines) < detectLines {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return named, 0
+		}
+	}
+
+	if len(lines) == 0 {
+		return Unknown, 0
+	}
+	sniffed, confidence := SniffShellType(lines)
+	switch {
+	case sniffed == Unknown && named == Unknown:
+		return Unknown, 0
+	case sniffed == Unknown:
+		return named, 0.5
+	case sniffed == named:
+		return sniffed, (confidence + 1) / 2
+	case named != Unknown && confidence <= weakConfidence:
+		return named, 0.5
+	}
+	return sniffed, confidence
+}
+
+// SniffShellType guesses which shell wrote the given lines of a history file.
+// Returns the shell type with the most marker lines and the fraction of all marker
+// lines that agree with it, at most weakConfidence if they're all weak markers, or
+// Unknown and 0 if none of the lines are distinctive.
+func SniffShellType(lines []string) (Type, float64) {
+	votes := map[Type]int{}
+	strong := map[Type]bool{}
+	total := 0
+	for _, line := range lines {
+		for _, marker := range historyLineMarkers {
+			if marker.regEx.MatchString(line) {
+				votes[marker.shellType]++
+				strong[marker.shellType] = strong[marker.shellType] || !marker.weak
+				total++
+				break
+			}
+		}
+	}
+	if total == 0 {
+		return Unknown, 0
+	}
+
+	best := Type(Unknown)
+	for _, shellType := range []Type{Zsh, Fish, Bash, PowerShell, Nu} {
+		if votes[shellType] > votes[best] {
+			best = shellType
+		}
+	}
+	confidence := float64(votes[best]) / float64(total)
+	if !strong[best] && confidence > weakConfidence {
+		confidence = weakConfidence
+	}
+	return best, confidence
+}
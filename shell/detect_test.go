@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffShellType(t *testing.T) {
+	shellType, confidence := SniffShellType([]string{": 1584112360:0;ls", ": 1584112361:3;git status"})
+	assert.Equal(t, Type(Zsh), shellType)
+	assert.Equal(t, 1.0, confidence)
+
+	shellType, _ = SniffShellType([]string{"- cmd: ls", "  when: 1591025337", "  paths:", "    - foo"})
+	assert.Equal(t, Type(Fish), shellType)
+
+	shellType, _ = SniffShellType([]string{"ls", "#1591025337", "git status"})
+	assert.Equal(t, Type(Bash), shellType)
+
+	shellType, _ = SniffShellType([]string{"Get-ChildItem -Recurse", "cd .."})
+	assert.Equal(t, Type(PowerShell), shellType)
+
+	shellType, confidence = SniffShellType([]string{"#1591025337", "ls", ": 1584112360:0;ls", ": 1584112361:0;pwd"})
+	assert.Equal(t, Type(Zsh), shellType)
+	assert.InDelta(t, 0.67, confidence, 0.01)
+
+	shellType, confidence = SniffShellType([]string{"ls", "cd src"})
+	assert.Equal(t, Type(Unknown), shellType)
+	assert.Equal(t, 0.0, confidence)
+}
+
+func TestDetectShellType(t *testing.T) {
+	file, err := ioutil.TempFile("", ".histfile")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(": 1584112360:0;ls\n: 1584112361:0;git status\n")
+	file.Close()
+
+	shellType, confidence := DetectShellType(file.Name())
+	assert.Equal(t, Type(Zsh), shellType)
+	assert.Equal(t, 1.0, confidence)
+
+	shellType, confidence = DetectShellType("/does/not/exist/.bash_history")
+	assert.Equal(t, Type(Bash), shellType)
+	assert.Equal(t, 0.0, confidence)
+}
+
+func TestDetectShellTypeKeepsFileName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "detect")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name string, contents string) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+
+	// Command substitutions end lines with a backtick, like PowerShell continuations
+	shellType, confidence := DetectShellType(write(".bash_history", "kill `cat /tmp/pid`\necho `date`\nls\n"))
+	assert.Equal(t, Type(Bash), shellType)
+	assert.Equal(t, 0.5, confidence)
+
+	// A cmdlet is only a weak sign of PowerShell
+	shellType, confidence = DetectShellType(write("bash_history.txt", "Get-ChildItem\nls\n"))
+	assert.Equal(t, Type(Bash), shellType)
+	assert.Equal(t, 0.5, confidence)
+	shellType, confidence = DetectShellType(write("history.txt", "Get-ChildItem\nls\n"))
+	assert.Equal(t, Type(PowerShell), shellType)
+	assert.Equal(t, weakConfidence, confidence)
+
+	// Nothing to go on
+	shellType, confidence = DetectShellType(write(".histfile", "ls\ncd src\n"))
+	assert.Equal(t, Type(Unknown), shellType)
+	assert.Equal(t, 0.0, confidence)
+	shellType, confidence = DetectShellType(write("empty", ""))
+	assert.Equal(t, Type(Unknown), shellType)
+	assert.Equal(t, 0.0, confidence)
+}
@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"path/filepath"
 	"strings"
 )
 
@@ -17,12 +18,19 @@ const (
 	// Zsh indicates the fish shell
 	Zsh = "Zsh"
 
+	// PowerShell indicates PowerShell (pwsh), reading history written by PSReadLine
+	PowerShell = "PowerShell"
+
+	// Nu indicates nushell
+	Nu = "Nu"
+
 	// Unknown indicates we aren't sure of the shell
 	Unknown = "Unknown"
 )
 
 // GetShellType tries to figure out the shell type from a history file name
 func GetShellType(historyFileName string) Type {
+	historyFileName = strings.ToLower(historyFileName)
 	if strings.Contains(historyFileName, "bash") {
 		return Bash
 	}
@@ -32,5 +40,11 @@ func GetShellType(historyFileName string) Type {
 	if strings.Contains(historyFileName, "zsh") {
 		return Zsh
 	}
+	if strings.Contains(historyFileName, "pwsh") || strings.Contains(historyFileName, "powershell") {
+		return PowerShell
+	}
+	if strings.Contains(historyFileName, "nushell") || filepath.Base(historyFileName) == "nu" {
+		return Nu
+	}
 	return Unknown
 }
@@ -6,7 +6,12 @@ import (
 	"github.com/warpdotdev/warp-cli-survey/shell"
 )
 
-// Response is an answer to a single question
+// Response is an answer to a single question. A question with a long history is
+// answered with several responses, see HistoryBatchSize. The first holds Answers,
+// CommandCounts and the first batch of HistoryLines; the rest have the same
+// RespondentID, QuestionID and QuestionNum, empty Answers and CommandCounts, and the
+// next batch of HistoryLines. Responses to a question should be combined rather than
+// each taken as the whole answer.
 type Response struct {
 	// RespondentID is a uuid for a survey respondent
 	RespondentID string
@@ -18,10 +23,19 @@ type Response struct {
 
 	// Answers is all of the answers to question.  Typically this is
 	// a single value but for multi-select answers it may be multiple.
+	// Empty in every response to a question but the first.
 	Answers []Answer
 
-	// HistoryLines is any history file lines associated with the answer
+	// HistoryLines is any history file lines associated with the answer,
+	// with only the fields the respondent chose to share filled in. Long
+	// histories are split across several responses, see HistoryBatchSize;
+	// only the first of them holds Answers and CommandCounts.
 	HistoryLines []HistoryLine
+
+	// CommandCounts is how many times each command was used, sent instead
+	// of HistoryLines if the respondent chose to share only that. Empty in
+	// every response to a question but the first.
+	CommandCounts []CommandCount
 }
 
 // Answer is a single answer to a question
@@ -34,6 +48,15 @@ type Answer struct {
 	IsOther      bool
 }
 
+// CommandCount is how many times a command was used in a history file
+type CommandCount struct {
+	RespondentID string
+	QuestionID   string
+	ShellType    shell.Type
+	Command      string
+	Count        int
+}
+
 // HistoryLine is a single command in a history file
 type HistoryLine struct {
 	RespondentID string
@@ -41,18 +64,76 @@ type HistoryLine struct {
 	FileName     string
 	ShellType    shell.Type
 	LineNum      int
-	Command      string
 	Subcommand   string
-	Options      []string
 	NumTokens    int
 
+	// Command is the name of the command. Scripts and commands we don't know are
+	// replaced by a placeholder for how they were run, e.g. <relative-script>,
+	// <absolute-path:bin> or <custom:.sh>.
+	Command string
+
+	// Options are the names of the options passed to the command, without
+	// their values. For commands we know, short flags are recorded by their
+	// long names, e.g. [extract gzip file] for tar -xzf.
+	Options []string
+
+	// SubcommandPath is every level of subcommand, e.g. [compute instances list]
+	// for gcloud compute instances list. Subcommand is its first element. Only
+	// known subcommands are sent, others are <unknown-subcommand>.
+	SubcommandPath []string
+
+	// Wrappers are commands like sudo or xargs that ran Command, outermost
+	// first, and EnvVars the names (never the values) of variables set for it.
+	Wrappers []string
+	EnvVars  []string
+
+	// Position is the index of the command among the commands on its line,
+	// PipelinePosition its index within its pipeline, and Operator the operator
+	// following it (|, |&, &&, ||, ; or &) or empty if it is last on the line.
+	Position         int
+	PipelinePosition int
+	Operator         string
+
 	// Length is the number of characters in the command.
 	Length int
 
-	// Sha1 is a hash of the entire c ommand
-	Sha1 string
+	// Hash is the hex HMAC-SHA256 of the entire command, keyed with a random
+	// key generated for each respondent that is never uploaded. Hashes can be
+	// compared to find repeated commands within one respondent's history, but
+	// not across respondents, and can't be reversed with a dictionary.
+	// Empty if the respondent chose not to send hashes.
+	Hash string
 
-	// CommandTimestamp is the time the command was issued or nil
-	// if that is not available.
+	// CommandTimestamp is the time the command was issued in UTC, rounded
+	// down to TimestampPrecision, or zero if that is not available or
+	// the user chose relative times.
 	CommandTimestamp time.Time
+
+	// TimezoneOffsetHours is the offset from UTC of the timezone the
+	// command was issued in, rounded to whole hours.
+	TimezoneOffsetHours int
+
+	// CommandOffset is how long after the first command in the history
+	// this one was issued, if the user chose relative times.
+	CommandOffset time.Duration
+
+	// TimestampPrecision is how precisely the user chose to share command
+	// times: Exact, Hour, Day or Relative.
+	TimestampPrecision string
+
+	// CommandDuration is how long the command ran for, or zero if
+	// that is not available.
+	CommandDuration time.Duration
+
+	// ExitStatus is the exit status of the command or nil if that is
+	// not available.
+	ExitStatus *int
+
+	// Directory is the working directory of the command with every path
+	// component redacted (e.g. ~/*/*), or empty if that is not available.
+	Directory string
+
+	// Session identifies the terminal session that ran the command, for
+	// histories kept in a file per session like macOS Terminal's, or empty.
+	Session string
 }
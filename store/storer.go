@@ -1,5 +1,9 @@
 package store
 
+// HistoryBatchSize is the most history lines sent with a single response. Longer
+// histories are sent as several responses to the same question.
+const HistoryBatchSize = 5000
+
 // Storer is an interface for recording responses
 type Storer interface {
 	Write(response Response)
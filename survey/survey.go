@@ -2,6 +2,7 @@ package survey
 
 import (
 	"bufio"
+	"errors"
 	"log"
 	"regexp"
 	"time"
@@ -9,8 +10,11 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 	"github.com/schollz/progressbar/v3"
@@ -22,6 +26,13 @@ import (
 
 const filePreviewLines = 40
 
+// streamedHistoryBytes is the size at which a history file is previewed and uploaded a
+// page at a time, rather than read into memory all at once
+const streamedHistoryBytes = 16 << 20
+
+// maxAuditWidth is the widest the original commands column of the audit view is padded to
+const maxAuditWidth = 60
+
 var emailRegEx = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
 // Positive thank yous for answering a question
@@ -33,8 +44,10 @@ var positives = []string{
 }
 
 // Start runs the survey and writes responses to the storer
-// historyFilePath is an optional argument specifying a history file to read
-func Start(storage store.Storer, emailer *store.Emailer, respondentID string, historyFilePath *string) {
+// historyFilePath is an optional argument specifying a history file to read, and
+// historyDirPath a directory with a history file per terminal session
+func Start(storage store.Storer, emailer *store.Emailer, respondentID string, historyFilePath *string,
+	historyDirPath *string) {
 	fmt.Println("\n> Welcome to the Warp survey! 👋")
 	fmt.Println("> This should take no more than 5-10 minutes. ⏲")
 	fmt.Println("\n> At Warp we are building a modern, collaborative command-line terminal for all developers.")
@@ -49,15 +62,18 @@ func Start(storage store.Storer, emailer *store.Emailer, respondentID string, hi
 	reader := bufio.NewReader(os.Stdin)
 	questions := io.Questions()
 	for i, q := range questions {
-		if q.ShouldShowFn == nil || q.ShouldShowFn(responsesByQuestionID) {
-			response := getValidAnswer(reader, q, responsesByQuestionID, historyFilePath)
+		// Always ask for the history file if one was passed on the command line,
+		// whatever shell the respondent uses.
+		if q.ShouldShowFn == nil || q.ShouldShowFn(responsesByQuestionID) ||
+			(q.Type == io.File && (historyFilePath != nil || historyDirPath != nil)) {
+			response := getValidAnswer(reader, q, responsesByQuestionID, historyFilePath, historyDirPath)
 			if response != nil {
 				responsesByQuestionID[q.ID] = response
 				if !response.Skipped {
 					// Execute in go routine so we can show progress
 					ch := make(chan int)
 					go func() {
-						storage.Write(response.Response(respondentID, i))
+						response.Write(storage, respondentID, i)
 						ch <- 1
 					}()
 
@@ -120,7 +136,7 @@ func summarizeResponses(responsesByQuestionID map[io.QuestionID]*io.Answer) stri
 // Shows the response prompt until the user has selected a valid answer
 // and returns that answer
 func getValidAnswer(reader *bufio.Reader, q io.Question,
-	responsesByQuestionID map[io.QuestionID]*io.Answer, historyFilePath *string) *io.Answer {
+	responsesByQuestionID map[io.QuestionID]*io.Answer, historyFilePath *string, historyDirPath *string) *io.Answer {
 	var response *io.Answer
 	for {
 		printQuestion(q)
@@ -137,7 +153,7 @@ func getValidAnswer(reader *bufio.Reader, q io.Question,
 		}
 
 		if response.PreviewFile {
-			previewFile(reader, q, response, responsesByQuestionID, historyFilePath)
+			previewFile(reader, q, response, responsesByQuestionID, historyFilePath, historyDirPath)
 		}
 
 		if response.IsDone {
@@ -156,22 +172,79 @@ func getValidAnswer(reader *bufio.Reader, q io.Question,
 }
 
 func previewFile(reader *bufio.Reader, q io.Question, response *io.Answer,
-	responsesByQuestionID map[io.QuestionID]*io.Answer, historyFilePath *string) {
+	responsesByQuestionID map[io.QuestionID]*io.Answer, historyFilePath *string, historyDirPath *string) {
 	var shellType shell.Type
-	if historyFilePath != nil {
-		shellType = shell.GetShellType(*historyFilePath)
+	if historyDirPath != nil {
+		// The directory is named after its shell, like ~/.zsh_sessions, or it's the
+		// shell the respondent uses
+		shellType = shell.GetShellType(filepath.Base(*historyDirPath))
+		if shellType == shell.Unknown {
+			shellType = shell.GetShellType(responsesByQuestionID["shell_type"].Text)
+		}
+	} else if historyFilePath != nil {
+		var confidence float64
+		shellType, confidence = shell.DetectShellType(*historyFilePath)
+		if shellType == shell.Unknown {
+			shellType = shell.GetShellType(responsesByQuestionID["shell_type"].Text)
+		}
+		if confidence < 0.5 {
+			fmt.Println("\nWe aren't sure which shell wrote", *historyFilePath, "- reading it as", shellType, "history.")
+		}
 	} else {
 		shellAnswer := responsesByQuestionID["shell_type"].Text
 		shellType = shell.GetShellType(shellAnswer)
 	}
-	history := q.GetShellHistoryFn(shellType, historyFilePath)
+	if historyDirPath == nil {
+		if path, ok := longHistoryFile(shellType, historyFilePath); ok &&
+			previewHistoryStream(reader, path, shellType, response) {
+			return
+		}
+	}
+	history := readHistory(q, shellType, historyFilePath, historyDirPath)
+	if historyFilePath == nil && historyDirPath == nil {
+		history = maybeIncludeOtherHistories(reader, shellType, history)
+		history = maybeUseAtuinHistory(reader, shellType, history)
+		history = maybeUseRecordedHistory(reader, shellType, history)
+		if history == nil || len(history.RedactedLines) == 0 {
+			history = maybeUseBuiltinHistory(reader, q, shellType, history)
+		}
+	}
+	if history == nil {
+		fmt.Println("Sorry, we weren't able to find your shell history, so there's nothing to upload.")
+		response.SkipThanks = true
+		return
+	}
 	fmt.Print("\nHere's a preview of your shell history file (",
 		history.FileName, " ", len(history.RedactedLines), " total commands) with options and arguments stripped:\n\n")
+	printHistoryNotes(history)
 
 	start := 0
+	showPreview := true
 	for {
-		printHistoryRange(history, start, start+filePreviewLines)
-		fmt.Println("Does this look OK to upload? [Y (yes, ok) / m (show more of the commands) / n (no, please don't upload)]")
+		if showPreview {
+			printHistoryRange(history, response.HistoryDetail, start, start+filePreviewLines)
+		}
+		showPreview = true
+		auditOption := ""
+		if response.HistoryDetail != io.CountsOnly {
+			auditOption = "a (compare with your original commands) / "
+		}
+		// Only full detail uploads when commands ran and their hashes
+		timesOption := ""
+		hashesOption := ""
+		if isFullDetail(response.HistoryDetail) {
+			timesOption = "t (choose how precisely to share when you ran commands) / "
+			hashesOption = "h (yes, but without command hashes) / "
+		}
+		fmt.Println("Does this look OK to upload? [Y (yes, ok) / m (show more of the commands) / " +
+			auditOption + "r (hide rare and uncommon commands and flags) / " + timesOption + hashesOption +
+			"n (no, please don't upload)]")
+		rangeOption := ""
+		if response.HistoryDetail != io.CountsOnly {
+			rangeOption = "x 12-30 (leave out commands 12 to 30) / "
+		}
+		fmt.Println("You can also leave out commands you'd rather not share: [/pattern (search your commands) / " +
+			rangeOption + "xc <command> (leave out every use of a command) / u (undo)]")
 		shareFileResponse, err := reader.ReadString('\n')
 		if err != nil {
 			fmt.Println("Oops, error reading your input. We won't upload it.")
@@ -180,11 +253,29 @@ func previewFile(reader *bufio.Reader, q io.Question, response *io.Answer,
 		}
 
 		trimmed := strings.TrimSpace(shareFileResponse)
-		if strings.EqualFold(trimmed, "m") {
+		if isEditCommand(trimmed) {
+			showPreview = editHistory(history, response.HistoryDetail, trimmed, len(rangeOption) > 0)
+			if start >= len(history.RedactedLines) {
+				start = 0
+			}
+		} else if strings.EqualFold(trimmed, "m") {
 			start += filePreviewLines
+		} else if len(auditOption) > 0 && strings.EqualFold(trimmed, "a") {
+			printAuditRange(history, response.HistoryDetail, start, start+filePreviewLines)
+			showPreview = false
+		} else if strings.EqualFold(trimmed, "r") {
+			suppressRare(history)
+			start = 0
+		} else if len(timesOption) > 0 && strings.EqualFold(trimmed, "t") {
+			chooseTimestampPrecision(reader, response)
+			start = 0
 		} else if len(trimmed) == 0 || strings.EqualFold(trimmed, "Y") {
 			response.History = history
 			break
+		} else if len(hashesOption) > 0 && strings.EqualFold(trimmed, "h") {
+			response.History = history
+			response.OmitHashes = true
+			break
 		} else {
 			fmt.Println("Ok, no problem, we won't upload it.")
 			response.SkipThanks = true
@@ -194,19 +285,575 @@ func previewFile(reader *bufio.Reader, q io.Question, response *io.Answer,
 
 }
 
-func printHistoryRange(history *history.ShellHistory, start int, end int) {
+// longHistoryFile returns the history file that would be previewed, if it is too long
+// to read into memory all at once
+func longHistoryFile(shellType shell.Type, historyFilePath *string) (string, bool) {
+	var path string
+	if historyFilePath != nil {
+		path = *historyFilePath
+	} else {
+		found, err := history.FindHistoryFile(shellType)
+		if err != nil {
+			return "", false
+		}
+		path = found
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < streamedHistoryBytes {
+		return "", false
+	}
+	return path, true
+}
+
+// previewHistoryStream previews a history file too long to read into memory a page at
+// a time, redacting it as it goes. If the user agrees to upload it, the answer reopens
+// the file to redact and upload it the same way. Returns false if the file can't be
+// streamed, so it can be previewed as usual.
+func previewHistoryStream(reader *bufio.Reader, path string, shellType shell.Type, response *io.Answer) bool {
+	stream, err := history.StreamHistoryFile(path, shellType)
+	if err != nil {
+		log.Println("Unable to stream history file", err)
+		return false
+	}
+	defer stream.Stop()
+
+	fmt.Print("\nYour shell history file (", path, ") is too long to preview all at once, so here are its "+
+		"commands a page at a time with options and arguments stripped. Leaving out commands and hiding rare "+
+		"ones aren't available for a history this long.\n\n")
+	next := 0
+	more := true
+	showPreview := true
+	for {
+		if showPreview {
+			next, more = printHistoryStreamPage(stream, response.HistoryDetail, next)
+		}
+		showPreview = true
+		moreOption := ""
+		if more {
+			moreOption = "m (show more of the commands) / "
+		}
+		timesOption := ""
+		hashesOption := ""
+		if isFullDetail(response.HistoryDetail) {
+			timesOption = "t (choose how precisely to share when you ran commands) / "
+			hashesOption = "h (yes, but without command hashes) / "
+		}
+		fmt.Println("Does this look OK to upload? [Y (yes, ok) / " + moreOption + timesOption + hashesOption +
+			"n (no, please don't upload)]")
+		shareFileResponse, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Println("Oops, error reading your input. We won't upload it.")
+			response.SkipThanks = true
+			return true
+		}
+
+		trimmed := strings.TrimSpace(shareFileResponse)
+		if len(moreOption) > 0 && strings.EqualFold(trimmed, "m") {
+			continue
+		} else if len(timesOption) > 0 && strings.EqualFold(trimmed, "t") {
+			chooseTimestampPrecision(reader, response)
+			showPreview = false
+		} else if len(trimmed) == 0 || strings.EqualFold(trimmed, "Y") {
+			response.OpenHistoryStream = func() (*history.HistoryStream, error) {
+				return history.StreamHistoryFile(path, shellType)
+			}
+			return true
+		} else if len(hashesOption) > 0 && strings.EqualFold(trimmed, "h") {
+			response.OpenHistoryStream = func() (*history.HistoryStream, error) {
+				return history.StreamHistoryFile(path, shellType)
+			}
+			response.OmitHashes = true
+			return true
+		} else {
+			fmt.Println("Ok, no problem, we won't upload it.")
+			response.SkipThanks = true
+			return true
+		}
+	}
+}
+
+// printHistoryStreamPage prints the next page of commands from a history stream,
+// numbered from start, and returns the number of the next command and whether the
+// stream may have more
+func printHistoryStreamPage(stream *history.HistoryStream, detail io.HistoryDetail, start int) (int, bool) {
+	next := start
+	for next < start+filePreviewLines {
+		redaction, ok := <-stream.Redactions
+		if !ok {
+			fmt.Print("That's all of your commands.\n\n")
+			return next, false
+		}
+		for _, redactedCmd := range redaction.Commands {
+			printNumberedCommand(next, formatRedactedCommand(redactedCmd, detail))
+			next++
+		}
+	}
+	fmt.Println()
+	return next, true
+}
+
+// isEditCommand returns true if the user's input is one of the commands for searching
+// or leaving out parts of their history
+func isEditCommand(input string) bool {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) == 0 {
+		return false
+	}
+	return strings.HasPrefix(input, "/") || fields[0] == "u" || fields[0] == "x" || fields[0] == "xc"
+}
+
+// editHistory runs one of the commands for searching or leaving out parts of the
+// history, and returns whether the preview should be shown again afterwards. Commands
+// are left out of the history itself, so they are never attached to the answer.
+func editHistory(shellHistory *history.ShellHistory, detail io.HistoryDetail, input string, allowRanges bool) bool {
+	fields := strings.Fields(input)
+	switch {
+	case strings.HasPrefix(input, "/"):
+		printSearchResults(shellHistory, detail, strings.TrimPrefix(input, "/"))
+		return false
+	case strings.EqualFold(input, "u"):
+		if restored := shellHistory.Undo(); restored > 0 {
+			fmt.Print("\nPut back ", restored, " commands.\n\n")
+		} else {
+			fmt.Print("\nThere's nothing to undo.\n\n")
+		}
+	case strings.EqualFold(fields[0], "xc"):
+		if len(fields) != 2 {
+			fmt.Print("\nPlease give the command to leave out, e.g. xc ssh\n\n")
+			return false
+		}
+		excluded := shellHistory.ExcludeCommand(fields[1])
+		fmt.Print("\nLeft out ", excluded, " uses of ", fields[1], ".\n\n")
+	case allowRanges:
+		start, end, err := parseLineRange(strings.Join(fields[1:], ""))
+		if err != nil {
+			fmt.Print("\nPlease give the commands to leave out by number, e.g. x 12-30 or x 7\n\n")
+			return false
+		}
+		excluded := shellHistory.ExcludeRange(start-1, end)
+		fmt.Print("\nLeft out ", excluded, " commands.\n\n")
+	default:
+		fmt.Print("\nPlease leave out commands with xc <command>, since only counts of each command are uploaded.\n\n")
+		return false
+	}
+	return true
+}
+
+// parseLineRange parses a range of line numbers like 12-30, or a single line number
+func parseLineRange(lineRange string) (int, int, error) {
+	bounds := strings.SplitN(lineRange, "-", 2)
+	start, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, err = strconv.Atoi(bounds[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < 1 || end < start {
+		return 0, 0, errors.New("Invalid range " + lineRange)
+	}
+	return start, end, nil
+}
+
+// printSearchResults prints the commands matching a pattern along with their numbers
+func printSearchResults(shellHistory *history.ShellHistory, detail io.HistoryDetail, pattern string) {
+	matches := shellHistory.Search(pattern)
+	if len(matches) == 0 {
+		fmt.Print("\nNo commands match ", pattern, ".\n\n")
+		return
+	}
+	fmt.Print("\nCommands matching ", pattern, ":\n")
+	shown := matches
+	if len(shown) > filePreviewLines {
+		shown = shown[:filePreviewLines]
+	}
+	for _, i := range shown {
+		printNumberedCommand(i, formatRedactedCommand(shellHistory.RedactedLines[i], detail))
+	}
+	if len(matches) > len(shown) {
+		fmt.Print("... plus ", len(matches)-len(shown), " other matching commands.\n")
+	}
+	fmt.Println()
+}
+
+func printNumberedCommand(i int, command string) {
+	fmt.Print(color.CyanString("%5d", i+1), "  ", command, "\n")
+}
+
+// suppressRare hides the commands, subcommands and flags the user rarely uses or that
+// aren't common, then shows what was hidden ahead of the preview
+func suppressRare(shellHistory *history.ShellHistory) {
+	suppressed := shellHistory.SuppressRare(history.DefaultMinCount)
+	if len(suppressed) == 0 {
+		fmt.Print("\nThere weren't any uncommon commands or flags, or any used fewer than ", history.DefaultMinCount,
+			" times, to hide.\n\n")
+		return
+	}
+	fmt.Print("\nHid the uncommon commands and flags, and any used fewer than ", history.DefaultMinCount,
+		" times. Here's the preview again:\n\n")
+	printHistoryNotes(shellHistory)
+}
+
+// timestampPrecisionChoices are the ways the user can choose to share when they ran
+// each command, in the order they are offered
+var timestampPrecisionChoices = []struct {
+	precision   history.TimestampPrecision
+	description string
+}{
+	{history.ExactTimestamps, "to the second"},
+	{history.HourTimestamps, "rounded to the hour"},
+	{history.DayTimestamps, "rounded to the day"},
+	{history.RelativeTimestamps, "only as the time since your first command"},
+}
+
+// isFullDetail returns true if command timestamps are uploaded at the given detail
+func isFullDetail(detail io.HistoryDetail) bool {
+	return detail != io.CommandsOnly && detail != io.CommandsAndSubcommands && detail != io.CountsOnly
+}
+
+// chooseTimestampPrecision asks the user how precisely to share when they ran each
+// command, leaving the current choice in place if they don't pick one
+func chooseTimestampPrecision(reader *bufio.Reader, response *io.Answer) {
+	fmt.Println("\nHow precisely should we share when you ran each command? Times are sent in UTC " +
+		"with your timezone's offset rounded to the hour.")
+	for i, choice := range timestampPrecisionChoices {
+		fmt.Println(color.CyanString(strconv.Itoa(i+1)), choice.description)
+	}
+	precisionResponse, err := reader.ReadString('\n')
+	if err != nil {
+		log.Println("Error reading answer", err)
+		return
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(precisionResponse))
+	if err != nil || choice < 1 || choice > len(timestampPrecisionChoices) {
+		fmt.Print("\nOk, we'll keep sharing times ", describeTimestampPrecision(response.TimestampPrecision), ".\n\n")
+		return
+	}
+	response.TimestampPrecision = timestampPrecisionChoices[choice-1].precision
+	fmt.Print("\nOk, we'll share times ", describeTimestampPrecision(response.TimestampPrecision), ".\n\n")
+}
+
+func describeTimestampPrecision(precision history.TimestampPrecision) string {
+	for _, choice := range timestampPrecisionChoices {
+		if choice.precision == precision {
+			return choice.description
+		}
+	}
+	return timestampPrecisionChoices[0].description
+}
+
+// readHistory reads the history in the directory passed on the command line, if there
+// is one, and otherwise the history file the question would
+func readHistory(q io.Question, shellType shell.Type, historyFilePath *string,
+	historyDirPath *string) *history.ShellHistory {
+	if historyDirPath != nil {
+		return history.RedactHistoryDir(*historyDirPath, shellType)
+	}
+	return q.GetShellHistoryFn(shellType, historyFilePath)
+}
+
+// maybeIncludeOtherHistories lists every history file we can find, for any shell, and
+// lets the user choose which to include. The ones they choose are merged into a single
+// history in the order the commands ran.
+func maybeIncludeOtherHistories(reader *bufio.Reader, shellType shell.Type,
+	fileHistory *history.ShellHistory) *history.ShellHistory {
+	candidates := history.DiscoverHistoryFiles()
+	if len(candidates) == 0 || (len(candidates) == 1 && fileHistory != nil) {
+		return fileHistory
+	}
+
+	fmt.Println("\nWe found these shell history files:")
+	for i, candidate := range candidates {
+		fmt.Printf("%s %s (%s history, %d KB, found via %s)\n", color.CyanString(strconv.Itoa(i+1)), candidate.Path,
+			candidate.ShellType, (candidate.Size+1023)/1024, candidate.Source)
+	}
+	defaultChoice := "none of them"
+	if fileHistory != nil {
+		defaultChoice = "just " + fileHistory.FileName
+	}
+	fmt.Println("Which would you like to include? [Enter (" + defaultChoice + ") / a (all of them) / " +
+		"the numbers of the files, e.g. 1,3]")
+	choiceResponse, err := reader.ReadString('\n')
+	if err != nil {
+		log.Println("Error reading answer", err)
+		return fileHistory
+	}
+	chosen, err := chooseHistoryCandidates(strings.TrimSpace(choiceResponse), candidates)
+	if err != nil {
+		fmt.Println("Sorry, we didn't understand that, so we'll keep", defaultChoice+".")
+		return fileHistory
+	}
+	if len(chosen) == 0 {
+		return fileHistory
+	}
+
+	// The files for the user's own shell go first, so the merged history is theirs
+	sort.SliceStable(chosen, func(i, j int) bool {
+		return chosen[i].ShellType == shellType && chosen[j].ShellType != shellType
+	})
+	histories := make([]*history.ShellHistory, 0, len(chosen))
+	for _, candidate := range chosen {
+		path := candidate.Path
+		if candidateHistory := history.RedactHistoryCandidate(candidate); candidateHistory != nil {
+			histories = append(histories, candidateHistory)
+		} else {
+			fmt.Println("Sorry, we couldn't read", path, "so we'll leave it out.")
+		}
+	}
+	if len(histories) == 0 {
+		return fileHistory
+	}
+	return history.MergeHistories(histories)
+}
+
+// chooseHistoryCandidates returns the history files the user picked, by number or
+// all of them for a
+func chooseHistoryCandidates(choice string, candidates []history.HistoryCandidate) ([]history.HistoryCandidate, error) {
+	chosen := make([]history.HistoryCandidate, 0)
+	if len(choice) == 0 {
+		return chosen, nil
+	}
+	if strings.EqualFold(choice, "a") {
+		return append(chosen, candidates...), nil
+	}
+	picked := map[int]bool{}
+	for _, field := range strings.FieldsFunc(choice, func(c rune) bool { return c == ',' || c == ' ' }) {
+		i, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		if i < 1 || i > len(candidates) {
+			return nil, errors.New("No history file numbered " + field)
+		}
+		if !picked[i] {
+			picked[i] = true
+			chosen = append(chosen, candidates[i-1])
+		}
+	}
+	return chosen, nil
+}
+
+// maybeUseBuiltinHistory asks the user whether we can read their history with their
+// shell's history builtin, when we couldn't find a history file with any commands in
+// it, and reads it if they agree. Nothing is run unless they do.
+func maybeUseBuiltinHistory(reader *bufio.Reader, q io.Question, shellType shell.Type,
+	fileHistory *history.ShellHistory) *history.ShellHistory {
+	if q.GetBuiltinHistoryFn == nil || len(q.BuiltinHistoryConsent) == 0 {
+		return fileHistory
+	}
+	commandLine, ok := history.BuiltinHistoryCommand(shellType)
+	if !ok {
+		return fileHistory
+	}
+	fmt.Println()
+	fmt.Println(fmt.Sprintf(q.BuiltinHistoryConsent, shellType, commandLine))
+	consentResponse, err := reader.ReadString('\n')
+	if err != nil || !strings.EqualFold(strings.TrimSpace(consentResponse), "y") {
+		return fileHistory
+	}
+	builtinHistory := q.GetBuiltinHistoryFn(shellType)
+	if builtinHistory == nil {
+		return fileHistory
+	}
+	return builtinHistory
+}
+
+// maybeUseAtuinHistory offers to upload the user's atuin history database, if they
+// have one, in place of the history file we found for their shell.
+func maybeUseAtuinHistory(reader *bufio.Reader, shellType shell.Type,
+	fileHistory *history.ShellHistory) *history.ShellHistory {
+	atuinFile, err := history.GetAtuinHistoryFile()
+	if err != nil {
+		return fileHistory
+	}
+	if fileHistory == nil {
+		fmt.Println("\nLooks like you use Atuin, so we'll use your Atuin history database (" + atuinFile + ").")
+	} else {
+		fmt.Println("\nLooks like you use Atuin. Would you like to share your Atuin history database (" +
+			atuinFile + ") instead of " + fileHistory.FileName + "? It usually has a more complete history. [Y / n]")
+		useAtuinResponse, err := reader.ReadString('\n')
+		trimmed := strings.TrimSpace(useAtuinResponse)
+		if err != nil || !(len(trimmed) == 0 || strings.EqualFold(trimmed, "Y")) {
+			return fileHistory
+		}
+	}
+	atuinHistory := history.RedactAtuinHistory(atuinFile, shellType)
+	if atuinHistory == nil {
+		return fileHistory
+	}
+	return atuinHistory
+}
+
+// maybeUseRecordedHistory offers the commands recorded by dsurvey record instead of the
+// history file, as they include how long each command ran and its exit status
+func maybeUseRecordedHistory(reader *bufio.Reader, shellType shell.Type,
+	fileHistory *history.ShellHistory) *history.ShellHistory {
+	logFile, err := history.GetRecordedHistoryFile()
+	if err != nil {
+		return fileHistory
+	}
+	recorded := history.RedactRecordedHistory(logFile, shellType)
+	if recorded == nil || len(recorded.RedactedLines) == 0 {
+		return fileHistory
+	}
+	if fileHistory == nil {
+		fmt.Println("\nWe'll use the commands you recorded with dsurvey record (" + logFile + ").")
+		return recorded
+	}
+	fmt.Println("\nYou've recorded " + strconv.Itoa(len(recorded.RedactedLines)) + " commands with dsurvey record. " +
+		"Would you like to share them (" + logFile + ") instead of " + fileHistory.FileName + "? They include how long " +
+		"each command ran and whether it failed, but only go back to when you started recording. [Y / n]")
+	useRecordedResponse, err := reader.ReadString('\n')
+	trimmed := strings.TrimSpace(useRecordedResponse)
+	if err != nil || !(len(trimmed) == 0 || strings.EqualFold(trimmed, "Y")) {
+		return fileHistory
+	}
+	return recorded
+}
+
+// printHistoryNotes explains anything in the history that was left out or replaced
+func printHistoryNotes(shellHistory *history.ShellHistory) {
+	if shellHistory.UnattributedLines > 0 {
+		fmt.Print("(", shellHistory.UnattributedLines, " lines of the file couldn't be matched to a command or its timestamp)\n\n")
+	}
+	if len(shellHistory.SuppressedLines) > 0 {
+		total, reasons := describeCounts(shellHistory.SuppressedLines)
+		fmt.Print("(We left out ", total, " lines that looked like they held secrets: ", reasons, ")\n\n")
+	}
+	if masked := shellHistory.MaskedSecrets(); len(masked) > 0 {
+		total, reasons := describeCounts(masked)
+		fmt.Print("(We removed the values of ", total, " options that looked like secrets: ", reasons, ")\n\n")
+	}
+	if len(shellHistory.SuppressedRare) > 0 {
+		total, items := describeCounts(shellHistory.SuppressedRare)
+		fmt.Print("(We hid ", total, " uses of rare commands, subcommands, flags, wrappers and variables, shown as ",
+			history.RareCommand, ", ", history.RareSubcommand, ", ", history.RareFlags, ", ", history.RareWrapper,
+			" or ", history.RareEnvVar, ": ", items, ")\n\n")
+	}
+	if excluded := shellHistory.Excluded(); excluded > 0 {
+		fmt.Print("(You left out ", excluded, " commands)\n\n")
+	}
+	if unknown := shellHistory.UnknownSubcommands(); unknown > 0 {
+		fmt.Print("(", unknown, " commands had a subcommand we don't know, shown as ", history.UnknownSubcommand,
+			" - we only upload subcommands from our list of known ones)\n\n")
+	}
+}
+
+// describeCounts totals counts by reason and lists them, e.g. 2 GitHub token, 1 private key
+func describeCounts(counts map[string]int) (int, string) {
+	reasons := make([]string, 0, len(counts))
+	total := 0
+	for reason, count := range counts {
+		reasons = append(reasons, strconv.Itoa(count)+" "+reason)
+		total += count
+	}
+	sort.Strings(reasons)
+	return total, strings.Join(reasons, ", ")
+}
+
+// printHistoryRange prints the commands from start to end as they would be uploaded
+// with the given level of detail
+func printHistoryRange(history *history.ShellHistory, detail io.HistoryDetail, start int, end int) {
+	if detail == io.CountsOnly {
+		printCommandCountRange(history, start, end)
+		return
+	}
 	if end > len(history.RedactedLines) {
 		end = len(history.RedactedLines)
 	}
 	if start >= end {
 		start = end
 	}
-	for _, redactedCmd := range history.RedactedLines[start:end] {
-		fmt.Println(redactedCmd.Preview())
+	for i, redactedCmd := range history.RedactedLines[start:end] {
+		printNumberedCommand(start+i, formatRedactedCommand(redactedCmd, detail))
 	}
 	fmt.Print("... plus ", len(history.RedactedLines)-end, " other redacted commands.\n\n")
 }
 
+// formatRedactedCommand returns a command as it would be uploaded with the given level
+// of detail
+func formatRedactedCommand(redactedCmd *history.RedactedCommand, detail io.HistoryDetail) string {
+	switch detail {
+	case io.CommandsOnly, io.CountsOnly:
+		return redactedCmd.Command
+	case io.CommandsAndSubcommands:
+		return strings.TrimSpace(redactedCmd.Command + " " + strings.Join(redactedCmd.SubcommandPath, " "))
+	default:
+		return redactedCmd.Preview()
+	}
+}
+
+// printAuditRange prints the commands from start to end as the user typed them, with
+// the parts we remove struck through in red, next to what would be uploaded. The
+// original commands are only ever printed here, never uploaded.
+func printAuditRange(shellHistory *history.ShellHistory, detail io.HistoryDetail, start int, end int) {
+	if end > len(shellHistory.RedactedLines) {
+		end = len(shellHistory.RedactedLines)
+	}
+	if start >= end {
+		start = end
+	}
+	commands := shellHistory.RedactedLines[start:end]
+	width := 0
+	for _, redactedCmd := range commands {
+		if length := auditLength(redactedCmd.Audit(false, false)); length > width {
+			width = length
+		}
+	}
+	if width > maxAuditWidth {
+		width = maxAuditWidth
+	}
+
+	removed := color.New(color.FgRed, color.CrossedOut)
+	fmt.Println("\nYour commands, with what we remove in red, next to what we'd upload:")
+	for i, redactedCmd := range commands {
+		fmt.Print(color.CyanString("%5d", start+i+1), "  ")
+		spans := redactedCmd.Audit(detail != io.CommandsOnly, isFullDetail(detail))
+		if len(spans) == 0 {
+			// Recorded commands are redacted before they're written, so there's
+			// nothing to compare with
+			spans = []history.AuditSpan{{Text: "(redacted when recorded)"}}
+		}
+		for _, span := range spans {
+			if span.Removed {
+				removed.Print(span.Text)
+			} else {
+				fmt.Print(span.Text)
+			}
+		}
+		if padding := width - auditLength(spans); padding > 0 {
+			fmt.Print(strings.Repeat(" ", padding))
+		}
+		fmt.Println("  │ ", formatRedactedCommand(redactedCmd, detail))
+	}
+	fmt.Println()
+}
+
+func auditLength(spans []history.AuditSpan) int {
+	length := 0
+	for _, span := range spans {
+		length += utf8.RuneCountInString(span.Text)
+	}
+	return length
+}
+
+func printCommandCountRange(history *history.ShellHistory, start int, end int) {
+	counts := history.CommandCounts()
+	if end > len(counts) {
+		end = len(counts)
+	}
+	if start >= end {
+		start = end
+	}
+	for _, count := range counts[start:end] {
+		fmt.Println(count.Command, "used", count.Count, "times")
+	}
+	fmt.Print("... plus ", len(counts)-end, " other commands.\n\n")
+}
+
 func printQuestion(q io.Question) {
 	fgMagenta := color.New(color.FgMagenta)
 	fgMagenta.Print("> ", q.Text)